@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/leads-su/broker"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/history"
+	"github.com/leads-su/logger"
+	"github.com/spf13/cobra"
+)
+
+var HistoryCommand = &cobra.Command{
+	Use:   "history <key>",
+	Short: "Show history of a key",
+	Long:  "Show history of values applied to a Consul key (referred to by Consul path or variable name)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		applicationConfiguration := initializeApplicationConfiguration(broker.NewBroker())
+		entries, err := history.NewHistory(applicationConfiguration).Get(args[0])
+		if err != nil {
+			logger.Fatalf("cmd:history", "failed to retrieve history of `%s` - %s", args[0], err.Error())
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "APPLIED AT\tMODIFY INDEX\tHASH\tVALUE")
+		for _, entry := range entries {
+			fmt.Fprintf(writer, "%s\t%d\t%s\t%s\n", entry.AppliedAt.Local().Format(time.RFC3339), entry.ModifyIndex, entry.Hash, describeHistoryValue(entry))
+		}
+		writer.Flush()
+	},
+}
+
+// describeHistoryValue converts value of history entry into a single line which can be printed
+func describeHistoryValue(entry *history.Entry) string {
+	if entry.Deleted {
+		return "<deleted>"
+	}
+	if entry.Value == nil {
+		return "<redacted>"
+	}
+	content, err := json.Marshal(entry.Value)
+	if err != nil {
+		return fmt.Sprintf("%v", entry.Value)
+	}
+	return string(content)
+}
@@ -8,6 +8,10 @@ import (
 	"github.com/leads-su/consul-config-manager/pkg/config"
 	"github.com/leads-su/consul-config-manager/pkg/http"
 	"github.com/leads-su/consul-config-manager/pkg/providers/consul"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/history"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/hooks"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/validator"
 	"github.com/leads-su/consul-config-manager/pkg/providers/vault"
 	"github.com/leads-su/consul-config-manager/pkg/state"
 	"github.com/leads-su/logger"
@@ -39,7 +43,15 @@ var StartCommand = &cobra.Command{
 		}
 
 		if applicationConfiguration.Consul.Enabled {
-			go consul.NewConsul(applicationConfiguration)
+			consulParser := parser.NewParser(applicationConfiguration)
+			consulHistory := history.NewHistory(applicationConfiguration)
+			consulHooks := hooks.NewHooks(applicationConfiguration, eventsServer)
+			consulValidator := validator.NewValidator(applicationConfiguration)
+
+			consulServer := http.NewConsulServer(consulParser, consulHistory, consulValidator)
+			consulServer.RegisterRoutes()
+
+			go consul.NewConsul(applicationConfiguration, consulParser, consulHistory, consulHooks, consulValidator)
 		}
 
 		if applicationConfiguration.Vault.Enabled {
@@ -17,6 +17,7 @@ func main() {
 		ConfigurationEnvPrefix: "CCM",
 	})
 	app.RegisterCommand(cmd.StartCommand)
+	app.RegisterCommand(cmd.HistoryCommand)
 	app.RegisterCommand(commands.VersionCommand)
 
 	err := app.Start()
@@ -7,8 +7,9 @@ import (
 )
 
 type Agent struct {
-	Network      *Network      `mapstructure:"network"`
-	HealthChecks *HealthChecks `mapstructure:"health_check"`
+	Network      *Network          `mapstructure:"network"`
+	HealthChecks *HealthChecks     `mapstructure:"health_check"`
+	Labels       map[string]string `mapstructure:"labels"`
 }
 
 // InitializeDefaults create new agent config instance with default values
@@ -23,6 +24,7 @@ func InitializeDefaults() *Agent {
 			TTL:  true,
 			HTTP: false,
 		},
+		Labels: make(map[string]string),
 	}
 }
 
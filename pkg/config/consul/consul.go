@@ -1,5 +1,7 @@
 package consul
 
+import "time"
+
 type Consul struct {
 	Enabled    bool   `mapstructure:"enabled"`
 	DataCenter string `mapstructure:"datacenter"`
@@ -7,8 +9,40 @@ type Consul struct {
 	Addresses  Addresses `mapstructure:"addresses"`
 	Token      string    `mapstructure:"token"`
 	WriteTo    string    `mapstructure:"write_to"`
+	EmptyFiles string    `mapstructure:"empty_files"`
+	Naming     *Naming   `mapstructure:"naming"`
+	Mapping    *Mapping  `mapstructure:"mapping"`
+	History    *History  `mapstructure:"history"`
+
+	// Format is an output format of configuration files, it can be changed for keys under specific path with Formats
+	Format  string        `mapstructure:"format"`
+	Formats []*FormatRule `mapstructure:"formats"`
+
+	// Units is a list of rules which set units of duration and size values for matching files
+	Units []*UnitRule `mapstructure:"units"`
+
+	// Templates is a list of Go templates rendered with values received from Consul
+	Templates []*Template `mapstructure:"templates"`
+
+	// Hooks is a list of actions executed after configuration files are written, so applications can pick them up
+	Hooks []*Hook `mapstructure:"hooks"`
+
+	// Validators is a list of commands which check configuration files before they are moved into place
+	Validators []*Validator `mapstructure:"validators"`
+
+	// PlainValues is a list of key prefixes, values without CCM envelope under them are treated as plain strings
+	PlainValues []string `mapstructure:"plain_values"`
 }
 
+const (
+	// EmptyFilesTruncate keeps files which no longer have any values, but removes their contents
+	EmptyFilesTruncate = "truncate"
+	// EmptyFilesRemove removes files which no longer have any values
+	EmptyFilesRemove = "remove"
+	// EmptyFilesArchive moves files which no longer have any values to `<file>.archived`
+	EmptyFilesArchive = "archive"
+)
+
 // InitializeDefaults create new consul config instance with default values
 func InitializeDefaults() *Consul {
 	return &Consul{
@@ -21,7 +55,39 @@ func InitializeDefaults() *Consul {
 				Port:   8500,
 			},
 		},
-		Token:   "",
-		WriteTo: "/etc/ccm.d",
+		Token:      "",
+		WriteTo:    "/etc/ccm.d",
+		EmptyFiles: EmptyFilesTruncate,
+		Naming: &Naming{
+			Prefix:        "CONSUL_",
+			StripSegments: 0,
+			PreserveCase:  false,
+			Rules:         nil,
+		},
+		Mapping: &Mapping{
+			Depth:         2,
+			Filename:      "{name}",
+			Unmatched:     MappingUnmatchedDefault,
+			Ignore:        nil,
+			Rules:         nil,
+			Mode:          "0640",
+			DirectoryMode: "0750",
+			Owner:         "",
+			Group:         "",
+			DriftCheck:    time.Minute,
+		},
+		History: &History{
+			Enabled:     false,
+			WriteTo:     "/var/lib/ccm/history",
+			Limit:       100,
+			StoreValues: false,
+		},
+		Format:      FormatEnv,
+		Formats:     nil,
+		Units:       nil,
+		Templates:   nil,
+		Hooks:       nil,
+		Validators:  nil,
+		PlainValues: nil,
 	}
 }
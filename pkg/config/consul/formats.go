@@ -0,0 +1,24 @@
+package consul
+
+const (
+	// FormatEnv writes `KEY="value"` lines
+	FormatEnv = "env"
+	// FormatExport writes `export KEY='value'` lines which can be sourced by shell scripts
+	FormatExport = "export"
+	// FormatJSON writes JSON object
+	FormatJSON = "json"
+	// FormatYAML writes YAML mapping
+	FormatYAML = "yaml"
+	// FormatTOML writes TOML table
+	FormatTOML = "toml"
+	// FormatINI writes INI section
+	FormatINI = "ini"
+	// FormatProperties writes Java `.properties` file
+	FormatProperties = "properties"
+)
+
+// FormatRule describes output format of files with keys under given path
+type FormatRule struct {
+	Path   string `mapstructure:"path"`
+	Format string `mapstructure:"format"`
+}
@@ -0,0 +1,9 @@
+package consul
+
+// History describes how history of applied values is kept on disk
+type History struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	WriteTo     string `mapstructure:"write_to"`
+	Limit       int    `mapstructure:"limit"`
+	StoreValues bool   `mapstructure:"store_values"`
+}
@@ -0,0 +1,27 @@
+package consul
+
+import "time"
+
+// Hook describes action executed after file matching the pattern is written (or removed),
+// exactly one of `command`, `reload` and `pidfile` should be set
+type Hook struct {
+	// File is a pattern of the file path, relative to `consul.write_to` (template destinations are matched as configured)
+	File string `mapstructure:"file"`
+
+	// Command and Arguments describe command which is executed
+	Command   string   `mapstructure:"command"`
+	Arguments []string `mapstructure:"arguments"`
+
+	// Reload is a name of systemd unit which is reloaded with `systemctl reload`
+	Reload string `mapstructure:"reload"`
+
+	// PidFile is a path to the file with PID of the process which receives Signal (`HUP` by default)
+	PidFile string `mapstructure:"pidfile"`
+	Signal  string `mapstructure:"signal"`
+
+	// Debounce is a delay after the last write before hook is executed, so several writes trigger it once
+	Debounce time.Duration `mapstructure:"debounce"`
+
+	// Timeout is a time after which command is killed
+	Timeout time.Duration `mapstructure:"timeout"`
+}
@@ -0,0 +1,47 @@
+package consul
+
+import "time"
+
+const (
+	// MappingUnmatchedDefault writes keys which do not match any mapping rule to files built from `depth` and `filename`
+	MappingUnmatchedDefault = "default"
+	// MappingUnmatchedSkip does not write keys which do not match any mapping rule
+	MappingUnmatchedSkip = "skip"
+)
+
+// Mapping describes how Consul key paths are mapped to configuration files
+type Mapping struct {
+	// Depth is a number of leading path segments which form file path (e.g. `app/db/host` is written to `app/db` with depth 2)
+	Depth int `mapstructure:"depth"`
+	// Filename is a pattern of file name, `{name}` is replaced with the last segment of the file path and `{N}` with N-th segment of the key path
+	Filename string `mapstructure:"filename"`
+	// Unmatched defines what happens with keys which do not match any rule
+	Unmatched string `mapstructure:"unmatched"`
+	// Ignore is a list of patterns, keys matching any of them (or under the path matching any of them) are not written to files
+	Ignore []string       `mapstructure:"ignore"`
+	Rules  []*MappingRule `mapstructure:"rules"`
+
+	// Mode and DirectoryMode are permission modes (in octal notation) of configuration files and directories they are placed in
+	Mode          string `mapstructure:"mode"`
+	DirectoryMode string `mapstructure:"directory_mode"`
+	// Owner and Group own configuration files and their directories, user CCM runs as is used when empty
+	Owner string `mapstructure:"owner"`
+	Group string `mapstructure:"group"`
+	// DriftCheck is an interval of checking whether mode and ownership of written files were changed outside of CCM, zero disables the check
+	DriftCheck time.Duration `mapstructure:"drift_check"`
+}
+
+// MappingRule describes file keys under given prefix are written to
+type MappingRule struct {
+	Prefix string `mapstructure:"prefix"`
+	// File is a path of the file (without extension) all keys under the prefix are written to, it supports `{N}` placeholders
+	File     string `mapstructure:"file"`
+	Depth    int    `mapstructure:"depth"`
+	Filename string `mapstructure:"filename"`
+
+	// Mode, DirectoryMode, Owner and Group override the same settings of `consul.mapping` for keys under the prefix
+	Mode          string `mapstructure:"mode"`
+	DirectoryMode string `mapstructure:"directory_mode"`
+	Owner         string `mapstructure:"owner"`
+	Group         string `mapstructure:"group"`
+}
@@ -0,0 +1,15 @@
+package consul
+
+// Naming describes how Consul key paths are converted to variable names
+type Naming struct {
+	Prefix        string        `mapstructure:"prefix"`
+	StripSegments int           `mapstructure:"strip_segments"`
+	PreserveCase  bool          `mapstructure:"preserve_case"`
+	Rules         []*NamingRule `mapstructure:"rules"`
+}
+
+// NamingRule describes rename rule applied to keys under given path
+type NamingRule struct {
+	Path string `mapstructure:"path"`
+	Name string `mapstructure:"name"`
+}
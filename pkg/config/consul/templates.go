@@ -0,0 +1,14 @@
+package consul
+
+// Template describes Go `text/template` file which is rendered with values received from Consul
+type Template struct {
+	// Source is a path to the template file
+	Source string `mapstructure:"source"`
+	// Destination is a path rendered file is written to, relative paths are resolved against `consul.write_to`
+	Destination string `mapstructure:"destination"`
+	// Perms is a permission mode of rendered file in octal notation (e.g. `0644`)
+	Perms string `mapstructure:"perms"`
+	// Owner and Group own rendered file, user CCM runs as is used when empty
+	Owner string `mapstructure:"owner"`
+	Group string `mapstructure:"group"`
+}
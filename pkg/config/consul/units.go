@@ -0,0 +1,8 @@
+package consul
+
+// UnitRule describes units duration and size values are written in, for files matching the pattern
+type UnitRule struct {
+	File     string `mapstructure:"file"`
+	Duration string `mapstructure:"duration"`
+	Size     string `mapstructure:"size"`
+}
@@ -0,0 +1,17 @@
+package consul
+
+import "time"
+
+// Validator describes command which checks candidate file before it replaces the previous version,
+// the file is held back when command exits with non-zero status
+type Validator struct {
+	// File is a pattern of the file path, relative to `consul.write_to` (template destinations are matched as configured)
+	File string `mapstructure:"file"`
+
+	// Command and Arguments describe command which is executed, `{{file}}` is replaced with path of the candidate file
+	Command   string   `mapstructure:"command"`
+	Arguments []string `mapstructure:"arguments"`
+
+	// Timeout is a time after which command is killed and the file is held back
+	Timeout time.Duration `mapstructure:"timeout"`
+}
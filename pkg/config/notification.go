@@ -0,0 +1,44 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/notifier"
+	notifierPackage "github.com/leads-su/notifier"
+)
+
+// notificationTemplate is a template of notifications sent by Consul provider components
+var notificationTemplate = template.Must(template.New("").Parse(`
+*Message:*     {{ .Message }}
+*Application:* {{ .Application }}
+*Version:*     {{ .Version }}-{{ .ShaHash }}
+*Server:*      {{ .Address }}
+`))
+
+// SendNotification sends notification of given type, title is prefixed with hostname of the agent
+func (config *Config) SendNotification(notificationType int, title, message string) {
+	templateValues := struct {
+		Message     string
+		Application string
+		Version     string
+		ShaHash     string
+		Address     string
+	}{
+		Message:     message,
+		Application: "Consul Config Manager",
+		Version:     config.Application.Version,
+		ShaHash:     config.Application.CommitSha,
+		Address:     config.Agent.Address(),
+	}
+
+	var templateBuffer bytes.Buffer
+	if err := notificationTemplate.Execute(&templateBuffer, templateValues); err == nil {
+		config.Notifier.DeliverNotification(notifier.TELEGRAM, notifierPackage.NewNotification(notifierPackage.NotificationOptions{
+			Type:    notificationType,
+			Title:   fmt.Sprintf("%s - %s", config.Agent.Network.Hostname(), title),
+			Message: templateBuffer.String(),
+		}))
+	}
+}
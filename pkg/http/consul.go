@@ -0,0 +1,195 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	netHttp "net/http"
+
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/history"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/validator"
+)
+
+// ConsulServer describes structure of Consul provider information server
+type ConsulServer struct {
+	parser    *parser.Parser
+	history   *history.History
+	validator *validator.Validator
+}
+
+// NewConsulServer creates new instance of Consul provider information server
+func NewConsulServer(consulParser *parser.Parser, consulHistory *history.History, consulValidator *validator.Validator) *ConsulServer {
+	return &ConsulServer{
+		parser:    consulParser,
+		history:   consulHistory,
+		validator: consulValidator,
+	}
+}
+
+// RegisterRoutes registers list of routes supported by the Consul provider information server
+func (consulServer *ConsulServer) RegisterRoutes() *ConsulServer {
+	netHttp.HandleFunc("/config/references", consulServer.unresolvedReferencesHandler)
+	netHttp.HandleFunc("/config/schedule", consulServer.scheduleHandler)
+	netHttp.HandleFunc("/config/expirations", consulServer.expirationsHandler)
+	netHttp.HandleFunc("/config/rejected", consulServer.rejectedValuesHandler)
+	netHttp.HandleFunc("/config/quarantine", consulServer.quarantinedValuesHandler)
+	netHttp.HandleFunc("/config/graph", consulServer.graphHandler)
+	netHttp.HandleFunc("/config/explain", consulServer.explainHandler)
+	netHttp.HandleFunc("/config/history", consulServer.historyHandler)
+	netHttp.HandleFunc("/config/held-back", consulServer.heldBackFilesHandler)
+	return consulServer
+}
+
+// unresolvedReferencesHandler handles request for the list of references which could not be resolved
+func (consulServer *ConsulServer) unresolvedReferencesHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: "Successfully retrieved list of unresolved references",
+		Data:    consulServer.parser.UnresolvedReferences(),
+	})
+}
+
+// scheduleHandler handles request for the list of delayed values waiting to be published
+func (consulServer *ConsulServer) scheduleHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: "Successfully retrieved list of scheduled publications",
+		Data:    consulServer.parser.ScheduledPublications(),
+	})
+}
+
+// expirationsHandler handles request for the list of expiring values waiting to be reverted
+func (consulServer *ConsulServer) expirationsHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: "Successfully retrieved list of scheduled expirations",
+		Data:    consulServer.parser.ScheduledExpirations(),
+	})
+}
+
+// rejectedValuesHandler handles request for the list of values rejected by validation constraints
+func (consulServer *ConsulServer) rejectedValuesHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: "Successfully retrieved list of rejected values",
+		Data:    consulServer.parser.RejectedValues(),
+	})
+}
+
+// quarantinedValuesHandler handles request for the list of values which could not be decoded
+func (consulServer *ConsulServer) quarantinedValuesHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: "Successfully retrieved list of quarantined values",
+		Data:    consulServer.parser.QuarantinedValues(),
+	})
+}
+
+// graphHandler handles request for the dependency graph of all variables
+func (consulServer *ConsulServer) graphHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: "Successfully retrieved dependency graph",
+		Data:    consulServer.parser.DependencyGraph(),
+	})
+}
+
+// explainHandler handles request for provenance of a single variable
+func (consulServer *ConsulServer) explainHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	key := request.URL.Query().Get("key")
+	if key == "" {
+		response.WriteHeader(netHttp.StatusBadRequest)
+		json.NewEncoder(response).Encode(ResponseStructure{
+			Success: false,
+			Status:  netHttp.StatusBadRequest,
+			Message: "Query parameter `key` is required",
+			Data:    nil,
+		})
+		return
+	}
+
+	provenance, ok := consulServer.parser.Explain(key)
+	if !ok {
+		response.WriteHeader(netHttp.StatusNotFound)
+		json.NewEncoder(response).Encode(ResponseStructure{
+			Success: false,
+			Status:  netHttp.StatusNotFound,
+			Message: fmt.Sprintf("Key `%s` is not known", key),
+			Data:    nil,
+		})
+		return
+	}
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: fmt.Sprintf("Successfully retrieved provenance of `%s`", key),
+		Data:    provenance,
+	})
+}
+
+// historyHandler handles request for the history of values applied to a single key
+func (consulServer *ConsulServer) historyHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	key := request.URL.Query().Get("key")
+	if key == "" {
+		response.WriteHeader(netHttp.StatusBadRequest)
+		json.NewEncoder(response).Encode(ResponseStructure{
+			Success: false,
+			Status:  netHttp.StatusBadRequest,
+			Message: "Query parameter `key` is required",
+			Data:    nil,
+		})
+		return
+	}
+
+	entries, err := consulServer.history.Get(key)
+	if err != nil {
+		response.WriteHeader(netHttp.StatusNotFound)
+		json.NewEncoder(response).Encode(ResponseStructure{
+			Success: false,
+			Status:  netHttp.StatusNotFound,
+			Message: fmt.Sprintf("No history found for `%s`", key),
+			Data:    err.Error(),
+		})
+		return
+	}
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: fmt.Sprintf("Successfully retrieved history of `%s`", key),
+		Data:    entries,
+	})
+}
+
+// heldBackFilesHandler handles request for the list of files which candidate versions were rejected by validators
+func (consulServer *ConsulServer) heldBackFilesHandler(response netHttp.ResponseWriter, request *netHttp.Request) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(netHttp.StatusOK)
+	json.NewEncoder(response).Encode(ResponseStructure{
+		Success: true,
+		Status:  netHttp.StatusOK,
+		Message: "Successfully retrieved list of held back files",
+		Data:    consulServer.validator.HeldBackFiles(),
+	})
+}
@@ -73,6 +73,24 @@ func (eventServer *EventServer) Publish(streamID string, event *sse.Event) {
 	eventServer.Server().Publish(streamID, event)
 }
 
+// PublishEvent publishes event to event server at given stream id and appends it to the log of the stream
+func (eventServer *EventServer) PublishEvent(streamID string, event tasks.EventStructure) {
+	data, _ := json.Marshal(event)
+
+	eventServer.Publish(streamID, &sse.Event{
+		Data: data,
+	})
+
+	go func() {
+		newLine := []byte("\n")
+		data = append(data, newLine...)
+		err := eventServer.Storage().AppendBytesArrayToFile(eventServer.Storage().AbsolutePath(fmt.Sprintf("%s.log", streamID)), data, 0755)
+		if err != nil {
+			logger.Errorf("task:realtime", "failed to write data to file - %s", err.Error())
+		}
+	}()
+}
+
 // Server returns instance of SSE server
 func (eventServer *EventServer) Server() *sse.Server {
 	return eventServer.server
@@ -140,26 +158,12 @@ func (eventServer *EventServer) taskCreatorHandler(response netHttp.ResponseWrit
 	logger.Infof("tasks:manager", "created new stream - `%s`", task.StreamID())
 
 	runnerTask, err := task.ToRunnerTask(func(outputType int, outputLine string, outputSource int) {
-		eventResponse := tasks.EventStructure{
+		eventServer.PublishEvent(task.StreamID(), tasks.EventStructure{
 			Source:    outputSource,
 			Type:      outputType,
 			Line:      outputLine,
 			Timestamp: time.Now().Unix(),
-		}
-		data, _ := json.Marshal(eventResponse)
-
-		eventServer.Publish(task.StreamID(), &sse.Event{
-			Data: data,
 		})
-
-		go func() {
-			newLine := []byte("\n")
-			data = append(data, newLine...)
-			err := eventServer.Storage().AppendBytesArrayToFile(eventServer.Storage().AbsolutePath(task.SteamIDLogFile()), data, 0755)
-			if err != nil {
-				logger.Errorf("task:realtime", "failed to write data to file - %s", err.Error())
-			}
-		}()
 	})
 	if err != nil {
 		response.WriteHeader(netHttp.StatusBadRequest)
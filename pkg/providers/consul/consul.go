@@ -5,8 +5,11 @@ import (
 	consulAPI "github.com/hashicorp/consul/api"
 	"github.com/leads-su/broker"
 	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/history"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/hooks"
 	"github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
 	"github.com/leads-su/consul-config-manager/pkg/providers/consul/storage"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/validator"
 	consulClient "github.com/leads-su/consul/client"
 	consulService "github.com/leads-su/consul/service"
 	"github.com/leads-su/consul/state"
@@ -15,15 +18,36 @@ import (
 	"github.com/spf13/viper"
 	"os"
 	"os/signal"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
 	"syscall"
 	"time"
 )
 
+const (
+	// rolloutMetaPrefix is a prefix of service meta keys which contain rollout variants chosen by the agent
+	rolloutMetaPrefix = "rollout_"
+	// maxRolloutMeta is a number of rollout variants which fit into service meta (Consul allows up to 64 meta keys)
+	maxRolloutMeta = 40
+
+	// serviceInterval is an interval of service health checks, rollout variants in service meta are checked as often
+	serviceInterval = 10 * time.Second
+	// serviceTimeout is a timeout of HTTP health check of the service
+	serviceTimeout = 30 * time.Second
+	// serviceDeregisterAfter is a time after which service with failing TTL check is deregistered
+	serviceDeregisterAfter = time.Minute
+)
+
+// rolloutMetaPattern matches characters which are not allowed in service meta keys
+var rolloutMetaPattern = regexp.MustCompile(`[^a-z0-9_-]+`)
+
 // NewConsul creates new instance of Consul client
-func NewConsul(config *cfg.Config) {
+func NewConsul(config *cfg.Config, consulParser *parser.Parser, consulHistory *history.History, consulHooks *hooks.Hooks, consulValidator *validator.Validator) {
 	brokerInstance, messageChannel := initializeBroker()
 	stopChannel := make(chan bool, 1)
-	go run(brokerInstance, messageChannel, config, stopChannel)
+	go run(brokerInstance, messageChannel, config, consulParser, consulHistory, consulHooks, consulValidator, stopChannel)
 
 	restartRequested := false
 	restartInProgress := false
@@ -35,7 +59,7 @@ func NewConsul(config *cfg.Config) {
 				restartRequested = true
 			} else if restartRequested && !restartInProgress {
 				restartInProgress = true
-				go run(brokerInstance, messageChannel, config, stopChannel)
+				go run(brokerInstance, messageChannel, config, consulParser, consulHistory, consulHooks, consulValidator, stopChannel)
 				restartRequested = false
 				restartInProgress = false
 			}
@@ -49,11 +73,13 @@ func NewConsul(config *cfg.Config) {
 }
 
 // run initializes connection to Consul
-func run(brokerInstance *broker.Broker, messageChannel chan interface{}, config *cfg.Config, stopChannel chan bool) {
+func run(brokerInstance *broker.Broker, messageChannel chan interface{}, config *cfg.Config, consulParser *parser.Parser, consulHistory *history.History, consulHooks *hooks.Hooks, consulValidator *validator.Validator, stopChannel chan bool) {
 	client := createClientConfiguration(brokerInstance, messageChannel, config)
 	client.SelectBestServer().Connect()
 
-	service := registerService(config, client)
+	service := registerService(config, client, consulParser)
+	rolloutCheck := time.NewTicker(serviceInterval)
+	defer rolloutCheck.Stop()
 
 	updateChannel := make(chan consulAPI.KVPairs)
 	errorChannel := make(chan error)
@@ -64,17 +90,41 @@ func run(brokerInstance *broker.Broker, messageChannel chan interface{}, config
 		UpdateChannel: updateChannel,
 		ErrorChannel:  errorChannel,
 	}
-	consulParser := parser.NewParser()
 	consulStorage := storage.NewStorage(config, consulParser)
+	consulStorage.SetServiceCatalog(client.APIClient().Health())
+	consulStorage.SetWriteHandler(consulHooks.FileWritten)
+	consulStorage.SetCandidateValidator(consulValidator)
+	consulParser.SetFileResolver(consulStorage.ConfigurationFilePath)
+	scheduler := newScheduler(consulParser, consulStorage, consulHistory)
 
 	go consulWatcher.Start()
 	defer consulWatcher.Stop()
+	defer scheduler.Stop()
+	defer consulStorage.StopServiceWatches()
+
+	var driftCheck <-chan time.Time
+	if config.Consul.Mapping != nil && config.Consul.Mapping.DriftCheck > 0 {
+		driftTicker := time.NewTicker(config.Consul.Mapping.DriftCheck)
+		defer driftTicker.Stop()
+		driftCheck = driftTicker.C
+	}
 
 	for {
 		select {
 		case values := <-updateChannel:
 			consulParser.ProcessReceivedData(values)
 			consulStorage.ProcessChanges(consulParser.GenerateConfiguration())
+			consulHistory.Record(consulParser)
+			scheduler.Reschedule()
+			syncRolloutMeta(config, client, rolloutMeta(consulParser))
+		case <-rolloutCheck.C:
+			syncRolloutMeta(config, client, rolloutMeta(consulParser))
+		case <-scheduler.Channel():
+			scheduler.Publish()
+		case <-consulStorage.ServicesChanged():
+			consulStorage.RenderTemplates()
+		case <-driftCheck:
+			consulStorage.CheckPermissions()
 		case err := <-errorChannel:
 			fmt.Printf("%s\n", err.Error())
 		case <-stopChannel:
@@ -112,23 +162,29 @@ func initializeBroker() (*broker.Broker, chan interface{}) {
 }
 
 // registerService register consul service
-func registerService(config *cfg.Config, client *consulClient.Client) *consulService.Service {
+func registerService(config *cfg.Config, client *consulClient.Client, consulParser *parser.Parser) *consulService.Service {
+	extraMeta := map[string]string{
+		"config_name": viper.GetString("application.configuration_file"),
+		"config_path": viper.GetString("application.configuration_file_path"),
+		"log_path":    viper.GetString("application.log_path"),
+		"log_level":   viper.GetString("application.log_level"),
+		"environment": config.Environment,
+	}
+	for name, variant := range rolloutMeta(consulParser) {
+		extraMeta[name] = variant
+	}
+
 	service := consulService.NewService(consulService.Options{
-		Client:     client,
-		Name:       "ccm",
-		Scheme:     "http",
-		Host:       config.Agent.Address(),
-		Port:       config.Agent.Network.Port,
-		HttpServer: config.Agent.HealthChecks.HTTP,
-		Interval:   time.Second * 10,
-		Timeout:    time.Second * 30,
-		ExtraMeta: map[string]string{
-			"config_name": viper.GetString("application.configuration_file"),
-			"config_path": viper.GetString("application.configuration_file_path"),
-			"log_path":    viper.GetString("application.log_path"),
-			"log_level":   viper.GetString("application.log_level"),
-			"environment": config.Environment,
-		},
+		Client:          client,
+		Name:            "ccm",
+		Scheme:          "http",
+		Host:            config.Agent.Address(),
+		Port:            config.Agent.Network.Port,
+		HttpServer:      config.Agent.HealthChecks.HTTP,
+		Interval:        serviceInterval,
+		Timeout:         serviceTimeout,
+		DeregisterAfter: serviceDeregisterAfter,
+		ExtraMeta:       extraMeta,
 	})
 	err := service.Register()
 	if err != nil {
@@ -138,6 +194,98 @@ func registerService(config *cfg.Config, client *consulClient.Client) *consulSer
 	return service
 }
 
+// rolloutMeta converts rollout variants chosen by the agent into service meta (e.g. `rollout_app_db_host: new`)
+func rolloutMeta(consulParser *parser.Parser) map[string]string {
+	meta := make(map[string]string)
+	for _, decision := range consulParser.Rollouts() {
+		if len(meta) == maxRolloutMeta {
+			logger.Warnf("consul:service", "only %d rollout variants can be published in service meta, skipping the rest", maxRolloutMeta)
+			break
+		}
+		name := rolloutMetaPrefix + strings.Trim(rolloutMetaPattern.ReplaceAllString(strings.ToLower(decision.Path), "_"), "_")
+		if len(name) > 128 {
+			name = name[:128]
+		}
+		meta[name] = fmt.Sprintf("%s (%v%%)", decision.Variant, decision.Percentage)
+	}
+	return meta
+}
+
+// syncRolloutMeta publishes rollout variants in the meta of registered service whenever meta known to the agent
+// differs from them, so variants are published again after service is re-registered with its initial meta
+func syncRolloutMeta(config *cfg.Config, client *consulClient.Client, rollouts map[string]string) {
+	if err := updateRolloutMeta(config, client, rollouts); err != nil {
+		logger.Errorf("consul:service", "failed to publish rollout variants - %s", err.Error())
+	}
+}
+
+// updateRolloutMeta replaces rollout variants in the meta of already registered service, service is re-registered
+// with the same ID and health checks, nothing is done until service is registered or when meta is up to date
+func updateRolloutMeta(config *cfg.Config, client *consulClient.Client, rollouts map[string]string) error {
+	services, err := client.APIClient().Agent().Services()
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		if service.Service != "ccm" || service.Address != config.Agent.Address() || service.Port != int(config.Agent.Network.Port) {
+			continue
+		}
+		meta, changed := replaceRolloutMeta(service.Meta, rollouts)
+		if !changed {
+			return nil
+		}
+		return client.APIClient().Agent().ServiceRegister(&consulAPI.AgentServiceRegistration{
+			ID:      service.ID,
+			Name:    service.Service,
+			Tags:    service.Tags,
+			Port:    service.Port,
+			Address: service.Address,
+			Meta:    meta,
+			Checks:  serviceChecks(config, service.ID),
+		})
+	}
+	return nil
+}
+
+// replaceRolloutMeta replaces rollout variants in service meta, reporting whether registered variants were different
+func replaceRolloutMeta(serviceMeta map[string]string, rollouts map[string]string) (map[string]string, bool) {
+	meta := make(map[string]string)
+	registered := make(map[string]string)
+	for name, value := range serviceMeta {
+		if strings.HasPrefix(name, rolloutMetaPrefix) {
+			registered[name] = value
+		} else {
+			meta[name] = value
+		}
+	}
+	for name, value := range rollouts {
+		meta[name] = value
+	}
+	return meta, !reflect.DeepEqual(registered, rollouts)
+}
+
+// serviceChecks returns the same health checks service is registered with by `consulService.Service`,
+// TTL check starts as passing, since the service which is re-registered is already running
+func serviceChecks(config *cfg.Config, serviceID string) consulAPI.AgentServiceChecks {
+	checks := consulAPI.AgentServiceChecks{
+		{
+			CheckID:                        serviceID + "-ttl",
+			TTL:                            (serviceInterval + time.Duration(5)).String(),
+			Status:                         consulAPI.HealthPassing,
+			DeregisterCriticalServiceAfter: serviceDeregisterAfter.String(),
+		},
+	}
+	if config.Agent.HealthChecks.HTTP && runtime.GOOS != "windows" {
+		checks = append(checks, &consulAPI.AgentServiceCheck{
+			CheckID:  serviceID + "-http",
+			HTTP:     fmt.Sprintf("http://%s:%d/health", config.Agent.Address(), config.Agent.Network.Port),
+			Interval: serviceInterval.String(),
+			Timeout:  serviceTimeout.String(),
+		})
+	}
+	return checks
+}
+
 // deregisterService deregister consul service
 func deregisterService(service *consulService.Service) {
 	err := service.Deregister()
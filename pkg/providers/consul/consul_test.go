@@ -0,0 +1,75 @@
+package consul
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/leads-su/broker"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+)
+
+func TestRolloutMetaNames(t *testing.T) {
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Agent.Network.Address = "127.0.0.1"
+	consulParser := parser.NewParser(config)
+	consulParser.ProcessReceivedData(api.KVPairs{
+		{Key: "App/DB.Pool", Value: []byte(`{"type":"number","value":100,"rollout":{"new":200,"percentage":100}}`), ModifyIndex: 1},
+	})
+
+	meta := rolloutMeta(consulParser)
+	if value := meta["rollout_app_db_pool"]; value != "new (100%)" {
+		t.Fatalf("expected `rollout_app_db_pool: new (100%%)`, got %#v", meta)
+	}
+}
+
+func TestReplaceRolloutMeta(t *testing.T) {
+	registered := map[string]string{
+		"config_name":      "ccm",
+		"rollout_app_old":  "new (10%)",
+		"rollout_app_pool": "old (10%)",
+	}
+	rollouts := map[string]string{"rollout_app_pool": "new (20%)"}
+
+	meta, changed := replaceRolloutMeta(registered, rollouts)
+	if !changed {
+		t.Fatalf("expected changed rollout variants to be reported")
+	}
+	if len(meta) != 2 || meta["config_name"] != "ccm" || meta["rollout_app_pool"] != "new (20%)" {
+		t.Fatalf("unexpected meta %#v", meta)
+	}
+
+	if _, changed = replaceRolloutMeta(meta, rollouts); changed {
+		t.Fatalf("expected up to date meta not to be reported as changed")
+	}
+	if _, changed = replaceRolloutMeta(map[string]string{"config_name": "ccm"}, map[string]string{}); changed {
+		t.Fatalf("expected meta without rollouts not to be reported as changed")
+	}
+	if _, changed = replaceRolloutMeta(map[string]string{"config_name": "ccm"}, rollouts); !changed {
+		t.Fatalf("expected meta of re-registered service to be reported as changed")
+	}
+}
+
+func TestServiceChecksMatchRegistration(t *testing.T) {
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Agent.Network.Address = "127.0.0.1"
+	config.Agent.HealthChecks.HTTP = true
+
+	checks := serviceChecks(config, "ccm-host-127.0.0.1")
+	if checks[0].CheckID != "ccm-host-127.0.0.1-ttl" || checks[0].TTL == "" || checks[0].DeregisterCriticalServiceAfter == "" {
+		t.Fatalf("unexpected TTL check %#v", checks[0])
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if len(checks) != 2 || checks[1].CheckID != "ccm-host-127.0.0.1-http" || checks[1].HTTP == "" {
+		t.Fatalf("expected HTTP check to be kept, got %#v", checks)
+	}
+}
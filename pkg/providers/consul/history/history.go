@@ -0,0 +1,277 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	p "github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+	"github.com/leads-su/logger"
+	s "github.com/leads-su/storage"
+)
+
+// historyFileExtension is an extension of files which contain history of a single key
+const historyFileExtension = ".json"
+
+// Entry describes value of a single key applied at some moment
+type Entry struct {
+	Path        string      `json:"path"`
+	Variable    string      `json:"variable"`
+	ModifyIndex uint64      `json:"modify_index"`
+	Hash        string      `json:"hash"`
+	Value       interface{} `json:"value,omitempty"`
+	Deleted     bool        `json:"deleted"`
+	AppliedAt   time.Time   `json:"applied_at"`
+}
+
+type History struct {
+	sync.RWMutex
+	// config is an instance of application configuration
+	config *cfg.Config
+
+	// storage is an instance of storage
+	storage *s.Storage
+
+	// lastEntries is a list of the latest entries of every key, indexed by Consul path
+	lastEntries map[string]*Entry
+
+	// failing is set when history could not be read or written, so the failure is only reported once
+	failing bool
+}
+
+// NewHistory creates new instance of value history
+func NewHistory(config *cfg.Config) *History {
+	return &History{
+		config: config,
+		storage: s.NewStorage(s.Options{
+			WorkingDirectory: config.Consul.History.WriteTo,
+		}),
+	}
+}
+
+// Record appends entry for every key which value (or ModifyIndex) changed since the last recorded entry,
+// as well as for every key which is no longer present in configuration
+func (history *History) Record(parser *p.Parser) {
+	if !history.config.Consul.History.Enabled {
+		return
+	}
+	history.Lock()
+	defer history.Unlock()
+
+	if err := history.loadLastEntries(); err != nil {
+		history.reportFailure(fmt.Sprintf("failed to load value history - %s", err.Error()))
+		return
+	}
+
+	liveData := parser.LiveData()
+	appliedAt := time.Now().UTC()
+	present := make(map[string]bool)
+	for _, provenance := range parser.DependencyGraph() {
+		value, published := liveData[provenance.Key]
+		if !published {
+			continue
+		}
+		present[provenance.Path] = true
+
+		hash, err := history.hashValue(value)
+		if err != nil {
+			logger.Errorf("consul:history", "failed to compute hash of `%s` - %s", provenance.Path, err.Error())
+			continue
+		}
+		last, exists := history.lastEntries[provenance.Path]
+		if exists && !last.Deleted && last.Hash == hash && last.ModifyIndex == provenance.ModifyIndex {
+			continue
+		}
+
+		entry := &Entry{
+			Path:        provenance.Path,
+			Variable:    provenance.Variable,
+			ModifyIndex: provenance.ModifyIndex,
+			Hash:        hash,
+			AppliedAt:   appliedAt,
+		}
+		if history.config.Consul.History.StoreValues {
+			entry.Value = value
+		}
+		history.appendEntry(entry)
+	}
+
+	for path, last := range history.lastEntries {
+		if present[path] || last.Deleted {
+			continue
+		}
+		history.appendEntry(&Entry{
+			Path:        path,
+			Variable:    last.Variable,
+			ModifyIndex: last.ModifyIndex,
+			Deleted:     true,
+			AppliedAt:   appliedAt,
+		})
+	}
+}
+
+// Get returns history of a key (referred to by Consul path or variable name), the latest entry goes first
+func (history *History) Get(pathOrVariable string) ([]*Entry, error) {
+	if !history.config.Consul.History.Enabled {
+		return nil, fmt.Errorf("value history is disabled, it can be enabled with `consul.history.enabled`")
+	}
+	history.RLock()
+	defer history.RUnlock()
+
+	path := strings.Trim(pathOrVariable, "/")
+	if !history.storage.Exists(history.historyFilePath(path)) {
+		var err error
+		if path, err = history.findVariablePath(pathOrVariable); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := history.readEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].AppliedAt.After(entries[j].AppliedAt)
+	})
+	return entries, nil
+}
+
+// appendEntry appends entry to the history file of the key, removing the oldest entries above the limit
+func (history *History) appendEntry(entry *Entry) {
+	entries, err := history.readEntries(entry.Path)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Errorf("consul:history", "failed to read history of `%s` - %s", entry.Path, err.Error())
+		return
+	}
+	entries = append(entries, entry)
+	if limit := history.config.Consul.History.Limit; limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if err = history.writeEntries(entry.Path, entries); err != nil {
+		history.reportFailure(fmt.Sprintf("failed to write history of `%s` - %s", entry.Path, err.Error()))
+		return
+	}
+	history.failing = false
+	history.lastEntries[entry.Path] = entry
+}
+
+// reportFailure logs failure to read or write history, further failures are not logged until history is written again
+func (history *History) reportFailure(message string) {
+	if history.failing {
+		return
+	}
+	history.failing = true
+	logger.Errorf("consul:history", "%s (further errors are not reported until history is written again)", message)
+}
+
+// loadLastEntries reads the latest entry of every key from disk, history is only read once after start
+func (history *History) loadLastEntries() error {
+	if history.lastEntries != nil {
+		return nil
+	}
+	lastEntries := make(map[string]*Entry)
+	files, err := ioutil.ReadDir(history.storage.AbsolutePath(""))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), historyFileExtension) {
+			continue
+		}
+		path, err := url.PathUnescape(strings.TrimSuffix(file.Name(), historyFileExtension))
+		if err != nil {
+			continue
+		}
+		entries, err := history.readEntries(path)
+		if err != nil {
+			logger.Warnf("consul:history", "skipping unreadable history of `%s` - %s", path, err.Error())
+			continue
+		}
+		if len(entries) != 0 {
+			lastEntries[path] = entries[len(entries)-1]
+		}
+	}
+	history.lastEntries = lastEntries
+	return nil
+}
+
+// findVariablePath looks for the Consul path of the key which was written under given variable name
+func (history *History) findVariablePath(variable string) (string, error) {
+	files, err := ioutil.ReadDir(history.storage.AbsolutePath(""))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, file := range files {
+		path, err := url.PathUnescape(strings.TrimSuffix(file.Name(), historyFileExtension))
+		if err != nil {
+			continue
+		}
+		entries, err := history.readEntries(path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Variable == variable {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no history recorded for `%s`", variable)
+}
+
+// readEntries reads history of a key from disk, the oldest entry goes first
+func (history *History) readEntries(path string) ([]*Entry, error) {
+	content, err := ioutil.ReadFile(history.historyFilePath(path))
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// writeEntries writes history of a key to temporary file, which then replaces the previous version
+func (history *History) writeEntries(path string, entries []*Entry) error {
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	filePath := history.historyFilePath(path)
+	if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	temporaryPath := filePath + ".tmp"
+	if err = ioutil.WriteFile(temporaryPath, content, 0640); err != nil {
+		return err
+	}
+	return os.Rename(temporaryPath, filePath)
+}
+
+// historyFilePath returns absolute path to the history file of a key,
+// Consul path is escaped so every key is stored in a single flat directory
+func (history *History) historyFilePath(path string) string {
+	return history.storage.AbsolutePath(url.PathEscape(path) + historyFileExtension)
+}
+
+// hashValue computes hash of the value, so changes can be tracked without storing the value itself
+func (history *History) hashValue(value interface{}) (string, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/leads-su/broker"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	p "github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+)
+
+// newTestHistory creates enabled history which is written to temporary directory, along with parser it is recorded from
+func newTestHistory(t *testing.T) (*History, *p.Parser) {
+	t.Helper()
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Agent.Network.Address = "127.0.0.1"
+	config.Consul.History.Enabled = true
+	config.Consul.History.WriteTo = t.TempDir()
+	return NewHistory(config), p.NewParser(config)
+}
+
+// apply processes snapshot of pairs and records it to history
+func apply(history *History, parser *p.Parser, pairs ...*api.KVPair) {
+	parser.ProcessReceivedData(pairs)
+	parser.GenerateConfiguration()
+	history.Record(parser)
+}
+
+func TestHistoryIsDisabledByDefault(t *testing.T) {
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	if config.Consul.History.Enabled {
+		t.Fatalf("expected history to be disabled by default")
+	}
+	if _, err = NewHistory(config).Get("app/host"); err == nil {
+		t.Fatalf("expected error when history is disabled")
+	}
+}
+
+func TestHistoryRecordsChangesAndDeletions(t *testing.T) {
+	history, parser := newTestHistory(t)
+	apply(history, parser, &api.KVPair{Key: "app/host", Value: []byte(`{"type":"string","value":"a"}`), ModifyIndex: 1})
+	apply(history, parser, &api.KVPair{Key: "app/host", Value: []byte(`{"type":"string","value":"a"}`), ModifyIndex: 1})
+	apply(history, parser, &api.KVPair{Key: "app/host", Value: []byte(`{"type":"string","value":"b"}`), ModifyIndex: 2})
+	apply(history, parser)
+
+	entries, err := history.Get("app/host")
+	if err != nil {
+		t.Fatalf("failed to read history - %s", err.Error())
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries (unchanged value is not recorded), got %d", len(entries))
+	}
+	if !entries[0].Deleted || entries[1].ModifyIndex != 2 || entries[2].ModifyIndex != 1 {
+		t.Fatalf("unexpected order of entries: %+v %+v %+v", entries[0], entries[1], entries[2])
+	}
+
+	byVariable, err := history.Get("CONSUL_APP_HOST")
+	if err != nil || len(byVariable) != 3 {
+		t.Fatalf("expected history to be found by variable name, got %d entries (%v)", len(byVariable), err)
+	}
+}
+
+func TestHistoryIsLimited(t *testing.T) {
+	history, parser := newTestHistory(t)
+	history.config.Consul.History.Limit = 2
+	history.config.Consul.History.StoreValues = true
+	for index, value := range []string{"a", "b", "c"} {
+		apply(history, parser, &api.KVPair{Key: "app/host", Value: []byte(`{"type":"string","value":"` + value + `"}`), ModifyIndex: uint64(index + 1)})
+	}
+
+	entries, err := history.Get("app/host")
+	if err != nil {
+		t.Fatalf("failed to read history - %s", err.Error())
+	}
+	if len(entries) != 2 || entries[0].Value != "c" || entries[1].Value != "b" {
+		t.Fatalf("expected two latest values to be kept, got %+v", entries)
+	}
+}
+
+func TestHistoryFailureIsRecorded(t *testing.T) {
+	history, parser := newTestHistory(t)
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatalf("failed to create file - %s", err.Error())
+	}
+	// History directory cannot be created under a regular file
+	history.config.Consul.History.WriteTo = filepath.Join(blocker, "history")
+	history.storage = NewHistory(history.config).storage
+
+	apply(history, parser, &api.KVPair{Key: "app/host", Value: []byte(`{"type":"string","value":"a"}`), ModifyIndex: 1})
+	if !history.failing {
+		t.Fatalf("expected failure to write history to be recorded")
+	}
+
+	history.config.Consul.History.WriteTo = t.TempDir()
+	history.storage = NewHistory(history.config).storage
+	apply(history, parser, &api.KVPair{Key: "app/host", Value: []byte(`{"type":"string","value":"b"}`), ModifyIndex: 2})
+	if history.failing {
+		t.Fatalf("expected failure to be cleared once history is written again")
+	}
+}
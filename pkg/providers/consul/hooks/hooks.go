@@ -0,0 +1,254 @@
+package hooks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+	"github.com/leads-su/consul-config-manager/pkg/tasks"
+	"github.com/leads-su/consul-config-manager/pkg/utils"
+	"github.com/leads-su/logger"
+	notifierPackage "github.com/leads-su/notifier"
+	"github.com/leads-su/runner"
+	"github.com/r3labs/sse/v2"
+)
+
+const (
+	// defaultDebounce is used for hooks without `debounce`
+	defaultDebounce = 2 * time.Second
+	// defaultSignal is sent to the process from pidfile when hook has no `signal`
+	defaultSignal = "HUP"
+)
+
+// EventLog receives output of hooks, it is implemented by `http.EventServer`
+type EventLog interface {
+	StartStream(streamID string) *sse.Stream
+	StopStream(streamID string)
+	PublishEvent(streamID string, event tasks.EventStructure)
+}
+
+// pendingHook describes hook waiting for debounce delay to pass
+type pendingHook struct {
+	timer *time.Timer
+	files map[string]bool
+}
+
+// Hooks runs actions configured in `consul.hooks` after configuration files matching them change
+type Hooks struct {
+	sync.Mutex
+	// config is an instance of application configuration
+	config *cfg.Config
+
+	// events is an event log hooks output is written to
+	events EventLog
+
+	// pending is a list of hooks waiting to be executed, indexed by position in `consul.hooks`
+	pending map[int]*pendingHook
+
+	// running is a list of locks which prevent the same hook from running concurrently
+	running map[int]*sync.Mutex
+}
+
+// NewHooks creates new instance of post-write hooks
+func NewHooks(config *cfg.Config, events EventLog) *Hooks {
+	return &Hooks{
+		config:  config,
+		events:  events,
+		pending: make(map[int]*pendingHook),
+		running: make(map[int]*sync.Mutex),
+	}
+}
+
+// FileWritten schedules every hook matching the file, hook is executed once the file (and other matching files)
+// were not written for the debounce delay
+func (hooks *Hooks) FileWritten(path string) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	for index, hook := range hooks.config.Consul.Hooks {
+		if hook == nil || !utils.MatchesFilePattern(hook.File, path) {
+			continue
+		}
+		pending, exists := hooks.pending[index]
+		if !exists {
+			pending = &pendingHook{files: make(map[string]bool)}
+			hooks.pending[index] = pending
+		} else {
+			pending.timer.Stop()
+		}
+		pending.files[path] = true
+
+		hookIndex, hookOptions := index, hook
+		pending.timer = time.AfterFunc(utils.DurationOrDefault(hook.Debounce, defaultDebounce), func() {
+			hooks.execute(hookIndex, hookOptions)
+		})
+	}
+}
+
+// execute runs hook for files collected while it was pending
+func (hooks *Hooks) execute(index int, hook *consul.Hook) {
+	hooks.Lock()
+	pending, exists := hooks.pending[index]
+	delete(hooks.pending, index)
+	lock, ok := hooks.running[index]
+	if !ok {
+		lock = &sync.Mutex{}
+		hooks.running[index] = lock
+	}
+	hooks.Unlock()
+	if !exists {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	files := make([]string, 0, len(pending.files))
+	for file := range pending.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	name := describeHook(hook)
+	streamID := fmt.Sprintf("hook-%d-%d", index, time.Now().UnixNano())
+	hooks.events.StartStream(streamID)
+	defer hooks.events.StopStream(streamID)
+	logger.Infof("consul:hooks", "running `%s` for %s, output is written to stream `%s`", name, strings.Join(files, ", "), streamID)
+
+	output := func(outputType int, line string, outputSource int) {
+		hooks.events.PublishEvent(streamID, tasks.EventStructure{
+			Source:    outputSource,
+			Type:      outputType,
+			Line:      line,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+
+	var err error
+	switch {
+	case hook.Command != "":
+		err = hooks.runCommand(hook, hook.Command, hook.Arguments, files, output)
+	case hook.Reload != "":
+		err = hooks.runCommand(hook, "systemctl", []string{"reload", hook.Reload}, files, output)
+	case hook.PidFile != "":
+		err = hooks.sendSignal(hook, output)
+	default:
+		err = fmt.Errorf("hook must have `command`, `reload` or `pidfile`")
+	}
+
+	if err != nil {
+		output(runner.StandardError, err.Error(), runner.SourceSystem)
+		errMsg := fmt.Sprintf("hook `%s` failed for %s (stream `%s`) - %s", name, strings.Join(files, ", "), streamID, err.Error())
+		logger.Error("consul:hooks", errMsg)
+		hooks.sendErrorNotification(errMsg)
+		return
+	}
+	logger.Infof("consul:hooks", "hook `%s` finished successfully", name)
+}
+
+// runCommand executes command, streaming its output line by line, list of written files is passed in `CCM_FILES` variable
+func (hooks *Hooks) runCommand(hook *consul.Hook, command string, arguments []string, files []string, output func(int, string, int)) error {
+	timeout := utils.DurationOrDefault(hook.Timeout, utils.DefaultCommandTimeout)
+	process := exec.Command(command, arguments...)
+	process.Env = append(os.Environ(), "CCM_FILES="+strings.Join(files, ","))
+	stdOut, err := process.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stdErr, err := process.StderrPipe()
+	if err != nil {
+		return err
+	}
+	commandTimer, err := utils.StartWithTimeout(process, timeout)
+	if err != nil {
+		return err
+	}
+
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go streamOutput(&readers, stdOut, runner.StandardOutput, output)
+	go streamOutput(&readers, stdErr, runner.StandardError, output)
+	readers.Wait()
+
+	err = process.Wait()
+	if commandTimer.Stop() {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		return err
+	}
+	output(runner.StandardOutput, fmt.Sprintf("exit status %d", process.ProcessState.ExitCode()), runner.SourceSystem)
+	return nil
+}
+
+// sendSignal sends signal to the process which PID is read from pidfile
+func (hooks *Hooks) sendSignal(hook *consul.Hook, output func(int, string, int)) error {
+	signalName := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(hook.Signal)), "SIG")
+	if signalName == "" {
+		signalName = defaultSignal
+	}
+	signal, ok := signals[signalName]
+	if !ok {
+		return fmt.Errorf("unknown signal `%s`", hook.Signal)
+	}
+
+	content, err := ioutil.ReadFile(hook.PidFile)
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return fmt.Errorf("invalid PID in `%s` - %s", hook.PidFile, err.Error())
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err = process.Signal(signal); err != nil {
+		return err
+	}
+	output(runner.StandardOutput, fmt.Sprintf("sent SIG%s to %d", signalName, pid), runner.SourceSystem)
+	return nil
+}
+
+// streamOutput sends every line of the command output to the event log
+func streamOutput(readers *sync.WaitGroup, reader io.Reader, outputType int, output func(int, string, int)) {
+	defer readers.Done()
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			output(outputType, line, runner.SourceCommand)
+		}
+	}
+}
+
+// describeHook returns short description of the hook used in logs and notifications
+func describeHook(hook *consul.Hook) string {
+	switch {
+	case hook.Command != "":
+		return strings.TrimSpace(hook.Command + " " + strings.Join(hook.Arguments, " "))
+	case hook.Reload != "":
+		return "systemctl reload " + hook.Reload
+	case hook.PidFile != "":
+		signalName := hook.Signal
+		if signalName == "" {
+			signalName = defaultSignal
+		}
+		return fmt.Sprintf("send %s to %s", signalName, hook.PidFile)
+	}
+	return hook.File
+}
+
+// sendErrorNotification sends error notification
+func (hooks *Hooks) sendErrorNotification(message string) {
+	hooks.config.SendNotification(notifierPackage.Error, "consul:hooks error", message)
+}
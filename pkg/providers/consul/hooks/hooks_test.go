@@ -0,0 +1,117 @@
+//go:build !windows
+// +build !windows
+
+package hooks
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leads-su/broker"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+	"github.com/leads-su/consul-config-manager/pkg/tasks"
+	"github.com/r3labs/sse/v2"
+)
+
+// recordingEventLog keeps lines published by hooks
+type recordingEventLog struct {
+	sync.Mutex
+	lines   []string
+	stopped chan bool
+}
+
+func (events *recordingEventLog) StartStream(streamID string) *sse.Stream {
+	return nil
+}
+
+func (events *recordingEventLog) StopStream(streamID string) {
+	events.stopped <- true
+}
+
+func (events *recordingEventLog) PublishEvent(streamID string, event tasks.EventStructure) {
+	events.Lock()
+	defer events.Unlock()
+	events.lines = append(events.lines, event.Line)
+}
+
+// output returns all lines published by hooks
+func (events *recordingEventLog) output() string {
+	events.Lock()
+	defer events.Unlock()
+	return strings.Join(events.lines, "\n")
+}
+
+// newTestHooks creates hooks with given configuration, output of hooks is recorded
+func newTestHooks(t *testing.T, hooks ...*consul.Hook) (*Hooks, *recordingEventLog) {
+	t.Helper()
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Consul.Hooks = hooks
+	events := &recordingEventLog{stopped: make(chan bool, 10)}
+	return NewHooks(config, events), events
+}
+
+// waitForHook waits until hook finishes
+func waitForHook(t *testing.T, events *recordingEventLog) {
+	t.Helper()
+	select {
+	case <-events.stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("hook did not finish")
+	}
+}
+
+func TestHookIsDebouncedAndReceivesFiles(t *testing.T) {
+	hooks, events := newTestHooks(t, &consul.Hook{
+		File:      "app/*",
+		Command:   "sh",
+		Arguments: []string{"-c", "echo files=$CCM_FILES"},
+		Debounce:  100 * time.Millisecond,
+	})
+	hooks.FileWritten("app/b.env")
+	hooks.FileWritten("app/a.env")
+	hooks.FileWritten("other/c.env")
+	waitForHook(t, events)
+
+	output := events.output()
+	if !strings.Contains(output, "files=app/a.env,app/b.env") {
+		t.Fatalf("expected hook to run once for both files, got:\n%s", output)
+	}
+	select {
+	case <-events.stopped:
+		t.Fatalf("expected hook to run only once")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestHookTimeoutKillsBackgroundProcesses(t *testing.T) {
+	hook := &consul.Hook{
+		File:      "app/*",
+		Command:   "sh",
+		Arguments: []string{"-c", "sleep 30 & sleep 30"},
+		Timeout:   200 * time.Millisecond,
+	}
+	hooks, _ := newTestHooks(t, hook)
+
+	started := time.Now()
+	err := hooks.runCommand(hook, hook.Command, hook.Arguments, []string{"app/a.env"}, func(int, string, int) {})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("hook was not stopped in time, took %s", elapsed)
+	}
+}
+
+func TestFailingHookReportsError(t *testing.T) {
+	hook := &consul.Hook{File: "app/*", Command: "sh", Arguments: []string{"-c", "exit 3"}}
+	hooks, _ := newTestHooks(t, hook)
+	if err := hooks.runCommand(hook, hook.Command, hook.Arguments, nil, func(int, string, int) {}); err == nil {
+		t.Fatalf("expected failing hook to return error")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package hooks
+
+import (
+	"os"
+	"syscall"
+)
+
+// signals lists signals which can be sent to the process from pidfile
+var signals = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package hooks
+
+import "os"
+
+// signals lists signals which can be sent to the process from pidfile, Windows only supports killing the process
+var signals = map[string]os.Signal{
+	"KILL": os.Kill,
+}
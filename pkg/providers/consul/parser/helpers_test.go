@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/leads-su/broker"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+)
+
+// newTestConfig creates application configuration which does not depend on network interfaces of the host
+func newTestConfig(t *testing.T) *cfg.Config {
+	t.Helper()
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Agent.Network.Address = "127.0.0.1"
+	return config
+}
+
+// newTestParser creates parser with test configuration
+func newTestParser(t *testing.T) *Parser {
+	t.Helper()
+	return NewParser(newTestConfig(t))
+}
+
+// pair creates Consul pair with given raw value
+func pair(key, value string) *api.KVPair {
+	return &api.KVPair{Key: key, Value: []byte(value), ModifyIndex: 1}
+}
+
+// generate processes snapshot of pairs and returns generated configuration
+func generate(parser *Parser, pairs ...*api.KVPair) map[string]interface{} {
+	parser.ProcessReceivedData(pairs)
+	return parser.GenerateConfiguration()
+}
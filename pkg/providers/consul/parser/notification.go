@@ -0,0 +1,18 @@
+package parser
+
+import notifierPackage "github.com/leads-su/notifier"
+
+// sendErrorNotification sends error notification
+func (parser *Parser) sendErrorNotification(message string) {
+	parser.sendNotification(notifierPackage.Error, "consul:parser error", message)
+}
+
+// sendInfoNotification sends informational notification
+func (parser *Parser) sendInfoNotification(message string) {
+	parser.sendNotification(notifierPackage.Info, "consul:parser", message)
+}
+
+// sendNotification sends notification of given type
+func (parser *Parser) sendNotification(notificationType int, title, message string) {
+	parser.config.SendNotification(notificationType, title, message)
+}
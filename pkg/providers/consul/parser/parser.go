@@ -2,88 +2,198 @@ package parser
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/consul/api"
-	"github.com/leads-su/logger"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
 )
 
 type Parser struct {
 	sync.RWMutex
-	referenceMap     map[string]string
-	liveData         map[string]interface{}
-	delayedData      map[string]*DelayedPublishing
-	referenceStorage *ReferenceStorage
+	config               *cfg.Config
+	referenceMap         map[string]string
+	referenceModifiers   map[string][]*ReferenceModifier
+	liveData             map[string]interface{}
+	delayedData          map[string]*DelayedPublishing
+	expiringData         map[string]*ExpiringValue
+	revertedData         map[string]time.Time
+	rejectedValues       map[string]RejectedValue
+	quarantinedValues    map[string]QuarantinedValue
+	templateData         map[string]string
+	templateConstraints  map[string]*ConsulValue
+	renderRejections     map[string]RejectedValue
+	snapshotKeys         map[string]string
+	keySources           map[string]*KeySource
+	dependencyGraph      map[string]*VariableProvenance
+	fileResolver         func(key string) string
+	referenceStorage     *ReferenceStorage
+	identity             *AgentIdentity
+	rollouts             map[string]RolloutDecision
+	filePermissions      map[string]*FilePermissions
+	unresolvedReferences []UnresolvedReference
 }
 
-func NewParser() *Parser {
+// NewParser creates new instance of parser
+func NewParser(config *cfg.Config) *Parser {
 	return &Parser{
-		referenceMap:     make(map[string]string),
-		liveData:         make(map[string]interface{}),
-		delayedData:      make(map[string]*DelayedPublishing),
-		referenceStorage: NewReferenceStorage(),
+		config:              config,
+		referenceMap:        make(map[string]string),
+		referenceModifiers:  make(map[string][]*ReferenceModifier),
+		liveData:            make(map[string]interface{}),
+		delayedData:         make(map[string]*DelayedPublishing),
+		expiringData:        make(map[string]*ExpiringValue),
+		revertedData:        make(map[string]time.Time),
+		rejectedValues:      make(map[string]RejectedValue),
+		quarantinedValues:   make(map[string]QuarantinedValue),
+		templateData:        make(map[string]string),
+		templateConstraints: make(map[string]*ConsulValue),
+		renderRejections:    make(map[string]RejectedValue),
+		snapshotKeys:        make(map[string]string),
+		referenceStorage:    NewReferenceStorage(),
 	}
 }
 
 // ProcessReceivedData process data received from Consul
 func (parser *Parser) ProcessReceivedData(pairs api.KVPairs) {
+	// Watcher always delivers full list of pairs, so references, interpolated, delayed and expiring values are collected
+	// from scratch and every key missing from the list is treated as removed from Consul
+	parser.resetReferenceData()
+	parser.resetKeySources()
+	parser.resetTemplateData()
+	parser.resetDelayedData()
+	parser.resetExpiringData()
+	parser.resetAgentIdentity()
+	parser.resetRollouts()
+	parser.resetFilePermissions()
+	previousKeys := parser.resetSnapshotKeys()
+	previousRejections := parser.resetRejectedValues()
+	previousQuarantine := parser.resetQuarantinedValues()
 	for _, entry := range pairs {
 		if entry.Value != nil {
 			key := parser.formatKey(entry.Key)
-			parser.referenceStorage.Set(entry.Key, key)
-			value := parser.processConsulValue(entry.Key, entry.Value)
+			parser.registerKey(entry.Key, entry.Key, key)
+			value, err := parser.processConsulValue(entry.Key, entry.Value)
+			if err != nil {
+				parser.setKeySource(entry.Key, &KeySource{ModifyIndex: entry.ModifyIndex})
+				parser.quarantineValue(entry.Key, key, err)
+				continue
+			}
+			parser.setKeySource(entry.Key, &KeySource{Type: value.Type, ModifyIndex: entry.ModifyIndex})
+			if err := parser.applyFilePermissions(entry.Key, value); err != nil {
+				parser.rejectValue(entry.Key, key, value, err)
+				continue
+			}
+			parser.applyEnvironmentOverride(value)
+			if err := parser.applyRollout(entry.Key, key, value); err != nil {
+				parser.rejectValue(entry.Key, key, value, err)
+				continue
+			}
+			if parser.applyVariants(value) {
+				// Variant takes precedence over rollout, so agent does not take part in the rollout of this key
+				parser.removeRolloutDecision(key)
+			}
 
-			if value.Type == "reference" {
-				targetKey := parser.formatKey(fmt.Sprintf("%v", value.Value))
-				parser.setReferenceValue(key, targetKey)
+			if err := parser.processValue(entry.Key, key, value); err != nil {
+				parser.removeRolloutDecision(key)
+				parser.rejectValue(entry.Key, key, value, err)
 			} else {
-				parser.processValue(key, value)
+				parser.processExpiration(entry.Key, key, value)
 			}
 		}
 	}
+	parser.removeDeletedKeys(previousKeys)
+	parser.reportRejectedValues(previousRejections)
+	parser.reportQuarantinedValues(previousQuarantine)
 }
 
 // GenerateConfiguration tries to process delayed and reference data and generates configuration
 func (parser *Parser) GenerateConfiguration() map[string]interface{} {
-	for key, delayedPublisher := range parser.delayedData {
-		if delayedPublisher.shouldPublish() {
-			parser.setDataValue(key, delayedPublisher.Value)
-			parser.removeDelayedDataValue(key)
-		} else {
-			parser.setDataValue(key, "consul_delayed_publishing")
-		}
-	}
+	parser.publishDelayedData()
+	parser.revertExpiredData()
 
-	// This cycle is needed to be able to resolve the target value for the nested references
-	// Due to the fact, that one reference can point to another, we need to get to the target
-	// value which they are referencing, this code is covering that use case
-	for key := range parser.referenceMap {
-		initialValue := key
-		targetValue := key
-
-		_, existsInMap := parser.referenceMap[targetValue]
-		for existsInMap {
-			targetValue = parser.referenceMap[targetValue]
-			_, existsInMap = parser.referenceMap[targetValue]
-		}
+	unresolved := parser.renderTemplates()
 
-		val, err := parser.getDataValue(initialValue, targetValue)
-		if err != nil {
-			logger.Errorf("consul:parser", "failed to retrieve reference value - %s", err)
-			continue
-		}
-		parser.setDataValue(initialValue, val)
-		parser.removeReferenceValue(initialValue)
-	}
+	// References are resolved after interpolated values are rendered, since one reference can point to another
+	// and the final target can be an interpolated value, the whole chain is followed to get to the real value
+	unresolved = append(unresolved, parser.resolveReferences()...)
+	parser.setUnresolvedReferences(unresolved)
+	parser.buildDependencyGraph(unresolved)
 
 	return parser.liveData
 }
 
+// LiveData returns copy of the live data map
+func (parser *Parser) LiveData() map[string]interface{} {
+	parser.RLock()
+	defer parser.RUnlock()
+	liveData := make(map[string]interface{}, len(parser.liveData))
+	for key, value := range parser.liveData {
+		liveData[key] = value
+	}
+	return liveData
+}
+
+// UnresolvedReferences returns list of references which could not be resolved during last configuration generation
+func (parser *Parser) UnresolvedReferences() []UnresolvedReference {
+	parser.RLock()
+	defer parser.RUnlock()
+	unresolved := make([]UnresolvedReference, len(parser.unresolvedReferences))
+	copy(unresolved, parser.unresolvedReferences)
+	return unresolved
+}
+
 // GetReferenceStorage returns instance of reference storage
 func (parser *Parser) GetReferenceStorage() *ReferenceStorage {
 	return parser.referenceStorage
 }
 
+// publishValue appends value to live data map, keeping interpolated strings for rendering
+func (parser *Parser) publishValue(key string, value interface{}) {
+	if interpolatedString, ok := value.(*InterpolatedString); ok {
+		parser.setTemplateDataValue(key, interpolatedString.Template, interpolatedString.Constraints)
+		return
+	}
+	parser.setDataValue(key, value)
+}
+
+// setUnresolvedReferences stores list of unresolved references and notifies about them whenever the list changes
+func (parser *Parser) setUnresolvedReferences(unresolved []UnresolvedReference) {
+	sort.Slice(unresolved, func(i, j int) bool {
+		return unresolved[i].Key < unresolved[j].Key
+	})
+
+	parser.Lock()
+	previous := parser.unresolvedReferences
+	parser.unresolvedReferences = unresolved
+	parser.Unlock()
+
+	if len(unresolved) == 0 || parser.sameUnresolvedReferences(previous, unresolved) {
+		return
+	}
+
+	var lines []string
+	for _, reference := range unresolved {
+		lines = append(lines, fmt.Sprintf("`%s` - %s", reference.Path, reference.Reason))
+	}
+	parser.sendErrorNotification(fmt.Sprintf("unable to resolve %d reference(s):\n%s", len(unresolved), strings.Join(lines, "\n")))
+}
+
+// sameUnresolvedReferences checks whether two lists of unresolved references are identical
+func (parser *Parser) sameUnresolvedReferences(previous, current []UnresolvedReference) bool {
+	if len(previous) != len(current) {
+		return false
+	}
+	for index := range previous {
+		if previous[index] != current[index] {
+			return false
+		}
+	}
+	return true
+}
+
 // shouldDelay simply checks if delayed parameter is not equal to null (not null = should be delayed)
 func (parser *Parser) shouldDelay(delayed interface{}) bool {
 	return delayed != nil
@@ -2,26 +2,41 @@ package parser
 
 import (
 	"fmt"
-	"strings"
-
-	"github.com/leads-su/logger"
 )
 
-// processArray processes ARRAY and appends it to live data map
-func (parser *Parser) processArray(key string, values []interface{}, delayed interface{}) {
-	var newValue []string
-	for _, value := range values {
-		newValue = append(newValue, fmt.Sprintf("%v", value))
+// arrayHandler handles ARRAY values, which are written as items joined by new line
+type arrayHandler struct{}
+
+// Decode converts raw value into array
+func (handler *arrayHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("`%v` (%T) is not a valid array value", raw, raw)
 	}
+	return values, nil
+}
 
-	if parser.shouldDelay(delayed) {
-		delayedPublisher, err := parser.newDelayedPublisher(values, delayed.(string))
-		if err != nil {
-			logger.Errorf("consul:parser:array", "failed to set delayed publisher for `%s` - %s", key, err.Error())
-		} else {
-			parser.setDelayedDataValue(key, delayedPublisher)
+// Validate checks array against `min_length` constraint and every item against `items` type and constraints
+func (handler *arrayHandler) Validate(context *ValueContext, decoded interface{}) error {
+	values := decoded.([]interface{})
+	if context.Value.MinLength != nil && len(values) < *context.Value.MinLength {
+		return fmt.Errorf("array must have at least %d item(s), got %d", *context.Value.MinLength, len(values))
+	}
+	for index, item := range values {
+		if err := validateArrayItem(context.Value, item); err != nil {
+			return fmt.Errorf("item #%d is invalid - %s", index, err.Error())
 		}
-	} else {
-		parser.setDataValue(key, strings.Join(newValue, "\n"))
 	}
+	return nil
+}
+
+// Render returns copy of array items, so references can pick or join them before they are written
+func (handler *arrayHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	values := decoded.([]interface{})
+	return append(make([]interface{}, 0, len(values)), values...), nil
+}
+
+// SupportsDelay reports that array values can be published with delay
+func (handler *arrayHandler) SupportsDelay() bool {
+	return true
 }
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// booleanHandler handles BOOLEAN values, which are written as `true` or `false`
+type booleanHandler struct{}
+
+// Decode converts raw value into boolean
+func (handler *booleanHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	return decodeBoolean(raw)
+}
+
+// Validate does nothing, as boolean values have no constraints
+func (handler *booleanHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return nil
+}
+
+// Render returns boolean as is
+func (handler *booleanHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	return decoded, nil
+}
+
+// SupportsDelay reports that boolean values can be published with delay
+func (handler *booleanHandler) SupportsDelay() bool {
+	return true
+}
+
+// decodeBoolean converts received value to boolean, allowing "true"/"false"/"1"/"0" strings and 1/0 numbers
+func decodeBoolean(value interface{}) (bool, error) {
+	switch typedValue := value.(type) {
+	case bool:
+		return typedValue, nil
+	case float64:
+		if typedValue == 1 {
+			return true, nil
+		}
+		if typedValue == 0 {
+			return false, nil
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(typedValue)) {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("`%v` (%T) is not a valid boolean value", value, value)
+}
@@ -0,0 +1,51 @@
+package parser
+
+import "testing"
+
+func TestDecodeBoolean(t *testing.T) {
+	tests := []struct {
+		raw      interface{}
+		expected bool
+		valid    bool
+	}{
+		{raw: true, expected: true, valid: true},
+		{raw: false, expected: false, valid: true},
+		{raw: " TRUE ", expected: true, valid: true},
+		{raw: "0", expected: false, valid: true},
+		{raw: float64(1), expected: true, valid: true},
+		{raw: float64(2), valid: false},
+		{raw: "yes", valid: false},
+		{raw: nil, valid: false},
+	}
+	for _, test := range tests {
+		decoded, err := decodeBoolean(test.raw)
+		if test.valid && (err != nil || decoded != test.expected) {
+			t.Fatalf("expected `%v` to be decoded as %v, got %v (%v)", test.raw, test.expected, decoded, err)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("expected `%v` to be rejected", test.raw)
+		}
+	}
+}
+
+func TestBooleanValueIsPublished(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/debug", `{"type":"boolean","value":"true"}`),
+		pair("app/cache", `{"type":"boolean","value":0}`),
+	)
+	if configuration["CONSUL_APP_DEBUG"] != true || configuration["CONSUL_APP_CACHE"] != false {
+		t.Fatalf("unexpected configuration %#v", configuration)
+	}
+}
+
+func TestInvalidBooleanValueIsRejected(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/debug", `{"type":"boolean","value":"maybe"}`))
+	if _, found := configuration["CONSUL_APP_DEBUG"]; found {
+		t.Fatalf("expected invalid boolean not to be published")
+	}
+	if rejected := parser.RejectedValues(); len(rejected) != 1 {
+		t.Fatalf("expected invalid boolean to be rejected, got %#v", rejected)
+	}
+}
@@ -2,42 +2,144 @@ package parser
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/leads-su/logger"
 )
 
 type ConsulValue struct {
-	Type    string      `json:"type"`
-	Delayed interface{} `json:"delayed"`
-	Value   interface{} `json:"value"`
+	Type     string      `json:"type"`
+	Delayed  interface{} `json:"delayed"`
+	Expires  interface{} `json:"expires"`
+	Fallback interface{} `json:"fallback"`
+	Value    interface{} `json:"value"`
+
+	// Literal marks plain values received without CCM envelope, such values are never interpolated
+	Literal bool `json:"-"`
+
+	// Environments contains values which override default value on agents with matching environment
+	Environments map[string]interface{} `json:"environments"`
+
+	// Rollout contains new and old values, new value is used on given percentage of agents
+	Rollout *ValueRollout `json:"rollout"`
+
+	// Variants contains ordered list of values selected by agent identity, first matching variant wins
+	Variants []*ValueVariant `json:"variants"`
+
+	// Modifiers contains ordered list of transformations applied to the referenced value
+	Modifiers []string `json:"modifiers"`
+
+	// Unit in which duration and size values are written
+	Unit string `json:"unit"`
+
+	// File specific options, `mode`, `owner` and `group` of other values apply to configuration file they are written to
+	Encoding string `json:"encoding"`
+	Path     string `json:"path"`
+	Mode     string `json:"mode"`
+	Owner    string `json:"owner"`
+	Group    string `json:"group"`
+
+	// Validation constraints
+	Required  bool          `json:"required"`
+	Min       *float64      `json:"min"`
+	Max       *float64      `json:"max"`
+	MinLength *int          `json:"min_length"`
+	Regex     string        `json:"regex"`
+	Enum      []interface{} `json:"enum"`
+	Items     string        `json:"items"`
 }
 
-// processConsulValue decodes value received from Consul into struct
-func (parser *Parser) processConsulValue(key string, value []byte) *ConsulValue {
+// processConsulValue decodes value received from Consul into struct, values without CCM envelope
+// are treated as plain strings for keys matching `consul.plain_values` prefixes
+func (parser *Parser) processConsulValue(key string, value []byte) (*ConsulValue, error) {
 	var processedValue *ConsulValue
 
 	err := json.Unmarshal(value, &processedValue)
+	if err == nil && processedValue != nil && processedValue.Type != "" {
+		return processedValue, nil
+	}
+
+	if parser.isPlainValuePath(key) {
+		return &ConsulValue{
+			Type:    "string",
+			Value:   string(value),
+			Literal: true,
+		}, nil
+	}
+
+	if err == nil {
+		err = fmt.Errorf("value does not have CCM envelope (missing `type` field)")
+	}
+	return nil, err
+}
+
+// processValue processes decoded value received from Consul using handler registered for its type,
+// value (or its fallback) which cannot be decoded or violates constraints is returned as error
+func (parser *Parser) processValue(path, key string, value *ConsulValue) error {
+	handler, err := getValueHandler(value.Type)
+	if err != nil {
+		return err
+	}
+	context := &ValueContext{
+		Path:  path,
+		Key:   key,
+		Value: value,
+	}
+
+	decoded, err := parser.decodeValue(handler, context, value.Value)
+	if err != nil {
+		return err
+	}
+	if value.Fallback != nil {
+		if _, err = parser.decodeValue(handler, context, value.Fallback); err != nil {
+			return fmt.Errorf("fallback value is invalid - %s", err.Error())
+		}
+	}
+
+	rendered, err := handler.Render(context, decoded)
 	if err != nil {
-		logger.Errorf("consul:parser", "failed to decode value for `%s` key - %s", key, err.Error())
-		return nil
+		return err
 	}
 
-	return processedValue
+	if value.Delayed != nil && !supportsDelay(handler) {
+		logger.Warnf("consul:parser", "`%s` values do not support delayed publishing, publishing `%s` immediately", value.Type, path)
+		parser.publishRenderedValue(path, key, rendered, nil)
+	} else {
+		parser.publishRenderedValue(path, key, rendered, value.Delayed)
+	}
+	return nil
 }
 
-// processValue processes decoded value received from Consul
-func (parser *Parser) processValue(key string, value *ConsulValue) {
-	switch value.Type {
-	case "array":
-		parser.processArray(key, value.Value.([]interface{}), value.Delayed)
-		break
-	case "number":
-		parser.processNumber(key, value.Value, value.Delayed)
-		break
-	case "string":
-		parser.processString(key, value.Value.(string), value.Delayed)
-		break
+// publishRenderedValue appends rendered value to live data map (or to delayed data map, if value should be delayed)
+func (parser *Parser) publishRenderedValue(path, key string, rendered interface{}, delayed interface{}) {
+	switch typedValue := rendered.(type) {
+	case *ReferenceTarget:
+		parser.setReferenceValue(key, parser.formatKey(typedValue.Path), typedValue.Modifiers)
+	case DerivedValues:
+		// Key itself is no longer published when its value changes from scalar to object, only derived values are
+		parser.removeDataValue(key)
+		parser.removeDelayedDataValue(key)
+		parser.removeTemplateDataValue(key)
+		for derivedPath, derivedValue := range typedValue {
+			derivedKey := parser.formatKey(derivedPath)
+			parser.registerKey(path, derivedPath, derivedKey)
+			parser.publishRenderedValue(path, derivedKey, derivedValue, delayed)
+		}
 	default:
-		logger.Errorf("consul:parser", "Unknown value type - %s (%T)", value.Type, value.Type)
+		if !parser.shouldDelay(delayed) {
+			parser.publishValue(key, rendered)
+			return
+		}
+		publishOn, ok := delayed.(string)
+		if !ok {
+			logger.Errorf("consul:parser", "failed to set delayed publisher for `%s` - publishing time must be a string", key)
+			return
+		}
+		delayedPublisher, err := parser.newDelayedPublisher(rendered, publishOn)
+		if err != nil {
+			logger.Errorf("consul:parser", "failed to set delayed publisher for `%s` - %s", key, err.Error())
+			return
+		}
+		parser.setDelayedDataValue(key, delayedPublisher)
 	}
 }
@@ -1,12 +1,22 @@
 package parser
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type DelayedPublishing struct {
 	Value     interface{}
 	PublishOn time.Time
 }
 
+// ScheduledPublication describes value which is waiting for its publishing time
+type ScheduledPublication struct {
+	Key       string    `json:"key"`
+	Path      string    `json:"path"`
+	PublishOn time.Time `json:"publish_on"`
+}
+
 // newDelayedPublisher creates new instance of delayed publisher
 func (parser *Parser) newDelayedPublisher(value interface{}, publishOn string) (*DelayedPublishing, error) {
 	publishOnTime, err := time.Parse(time.RFC3339, publishOn)
@@ -25,3 +35,48 @@ func (dp *DelayedPublishing) shouldPublish() bool {
 	publishTime := dp.PublishOn.UTC().Unix()
 	return localTime >= publishTime
 }
+
+// publishDelayedData publishes delayed values which reached their publishing time,
+// values which are still waiting keep previously published value in live data map
+func (parser *Parser) publishDelayedData() {
+	for key, delayedPublisher := range parser.delayedData {
+		if delayedPublisher.shouldPublish() {
+			parser.publishValue(key, delayedPublisher.Value)
+			parser.removeDelayedDataValue(key)
+		}
+	}
+}
+
+// NextPublishing returns the closest time at which one of delayed values should be published
+func (parser *Parser) NextPublishing() (time.Time, bool) {
+	parser.RLock()
+	defer parser.RUnlock()
+	var next time.Time
+	for _, delayedPublisher := range parser.delayedData {
+		if next.IsZero() || delayedPublisher.PublishOn.Before(next) {
+			next = delayedPublisher.PublishOn
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// ScheduledPublications returns list of values which are waiting to be published
+func (parser *Parser) ScheduledPublications() []ScheduledPublication {
+	parser.RLock()
+	publications := make([]ScheduledPublication, 0, len(parser.delayedData))
+	for key, delayedPublisher := range parser.delayedData {
+		publications = append(publications, ScheduledPublication{
+			Key:       key,
+			PublishOn: delayedPublisher.PublishOn,
+		})
+	}
+	parser.RUnlock()
+
+	for index := range publications {
+		publications[index].Path = parser.describeKey(publications[index].Key)
+	}
+	sort.Slice(publications, func(i, j int) bool {
+		return publications[i].PublishOn.Before(publications[j].PublishOn)
+	})
+	return publications
+}
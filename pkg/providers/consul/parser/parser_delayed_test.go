@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayedValueKeepsPreviousValueUntilPublishingTime(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/mode", `{"type":"string","value":"old"}`))
+
+	publishOn := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	configuration := generate(parser, pair("app/mode", `{"type":"string","value":"new","delayed":"`+publishOn.Format(time.RFC3339)+`"}`))
+	if value := configuration["CONSUL_APP_MODE"]; value != "old" {
+		t.Fatalf("expected previous value to be kept, got %#v", value)
+	}
+
+	next, ok := parser.NextPublishing()
+	if !ok || !next.Equal(publishOn) {
+		t.Fatalf("expected next publishing at %s, got %s (%v)", publishOn, next, ok)
+	}
+	publications := parser.ScheduledPublications()
+	if len(publications) != 1 || publications[0].Path != "app/mode" {
+		t.Fatalf("expected scheduled publication of `app/mode`, got %#v", publications)
+	}
+}
+
+func TestDelayedValueIsPublishedAtPublishingTime(t *testing.T) {
+	parser := newTestParser(t)
+	publishOn := time.Now().Add(time.Second).UTC().Format(time.RFC3339)
+	configuration := generate(parser, pair("app/mode", `{"type":"string","value":"new","delayed":"`+publishOn+`"}`))
+	if _, found := configuration["CONSUL_APP_MODE"]; found {
+		t.Fatalf("expected delayed value not to be published yet")
+	}
+
+	time.Sleep(2 * time.Second)
+	configuration = parser.GenerateConfiguration()
+	if value := configuration["CONSUL_APP_MODE"]; value != "new" {
+		t.Fatalf("expected delayed value to be published, got %#v", value)
+	}
+	if _, ok := parser.NextPublishing(); ok {
+		t.Fatalf("expected no more scheduled publications")
+	}
+}
+
+func TestDelayedValueInThePastIsPublishedImmediately(t *testing.T) {
+	parser := newTestParser(t)
+	publishOn := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	configuration := generate(parser, pair("app/mode", `{"type":"string","value":"new","delayed":"`+publishOn+`"}`))
+	if value := configuration["CONSUL_APP_MODE"]; value != "new" {
+		t.Fatalf("expected value to be published, got %#v", value)
+	}
+}
+
+func TestDelayedValueWithInvalidTimeIsNotPublished(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/mode", `{"type":"string","value":"new","delayed":"tomorrow"}`))
+	if _, found := configuration["CONSUL_APP_MODE"]; found {
+		t.Fatalf("expected value with invalid publishing time not to be published")
+	}
+}
@@ -0,0 +1,18 @@
+package parser
+
+import "github.com/leads-su/logger"
+
+// removeDeletedKeys removes values of keys which were present in previous snapshot but are missing from the current one
+func (parser *Parser) removeDeletedKeys(previousKeys map[string]string) {
+	for key, path := range previousKeys {
+		if _, exists := parser.snapshotKeys[key]; exists {
+			continue
+		}
+		logger.Infof("consul:parser", "`%s` was removed from Consul, removing `%s` from configuration", path, key)
+		parser.removeDataValue(key)
+		parser.removeDelayedDataValue(key)
+		parser.removeExpiringDataValue(key)
+		parser.removeRevertedDataValue(key)
+		parser.referenceStorage.remove(path, key)
+	}
+}
@@ -0,0 +1,54 @@
+package parser
+
+import "testing"
+
+func TestRemovedKeyIsRemovedFromConfiguration(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/db/host", `{"type":"string","value":"h"}`), pair("app/db/port", `{"type":"number","value":5432}`))
+
+	configuration := generate(parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+	if _, exists := configuration["CONSUL_APP_DB_PORT"]; exists {
+		t.Fatalf("removed key is still present in configuration: %v", configuration)
+	}
+	if configuration["CONSUL_APP_DB_HOST"] != "h" {
+		t.Fatalf("expected remaining key to be kept, got %v", configuration)
+	}
+}
+
+func TestScalarChangedToObjectRemovesScalarValue(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/db", `{"type":"string","value":"x"}`))
+
+	configuration := generate(parser, pair("app/db", `{"type":"object","value":{"host":"h"}}`))
+	if _, exists := configuration["CONSUL_APP_DB"]; exists {
+		t.Fatalf("stale scalar value is still present: %v", configuration)
+	}
+	if configuration["CONSUL_APP_DB_HOST"] != "h" {
+		t.Fatalf("expected derived value to be published, got %v", configuration)
+	}
+}
+
+func TestObjectChangedToScalarRemovesDerivedValues(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/db", `{"type":"object","value":{"host":"h","port":1}}`))
+
+	configuration := generate(parser, pair("app/db", `{"type":"string","value":"x"}`))
+	for _, key := range []string{"CONSUL_APP_DB_HOST", "CONSUL_APP_DB_PORT"} {
+		if _, exists := configuration[key]; exists {
+			t.Fatalf("stale derived value `%s` is still present: %v", key, configuration)
+		}
+	}
+	if configuration["CONSUL_APP_DB"] != "x" {
+		t.Fatalf("expected scalar value to be published, got %v", configuration)
+	}
+}
+
+func TestObjectFieldRemovedFromObject(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/db", `{"type":"object","value":{"host":"h","port":1}}`))
+
+	configuration := generate(parser, pair("app/db", `{"type":"object","value":{"host":"h"}}`))
+	if _, exists := configuration["CONSUL_APP_DB_PORT"]; exists {
+		t.Fatalf("removed field is still present: %v", configuration)
+	}
+}
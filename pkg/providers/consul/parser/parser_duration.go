@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnitString is a unit which writes duration as Go duration string (e.g. `1h30m0s`)
+const durationUnitString = "duration"
+
+// durationUnits lists units duration can be written in, with their size in nanoseconds
+var durationUnits = map[string]float64{
+	"ns": float64(time.Nanosecond),
+	"us": float64(time.Microsecond),
+	"ms": float64(time.Millisecond),
+	"s":  float64(time.Second),
+	"m":  float64(time.Minute),
+	"h":  float64(time.Hour),
+	"d":  float64(24 * time.Hour),
+}
+
+// durationUnitAliases maps human-readable unit names to duration units
+var durationUnitAliases = map[string]string{
+	"nanoseconds":  "ns",
+	"microseconds": "us",
+	"milliseconds": "ms",
+	"seconds":      "s",
+	"minutes":      "m",
+	"hours":        "h",
+	"days":         "d",
+}
+
+// durationDaysPattern matches duration starting with number of days (e.g. `2d12h`), which Go does not support
+var durationDaysPattern = regexp.MustCompile(`^([0-9]*\.?[0-9]+)d(.*)$`)
+
+// DurationValue describes duration which is written in requested unit
+type DurationValue struct {
+	Duration time.Duration
+	Unit     string
+}
+
+// TargetUnit returns unit requested by the value itself
+func (value *DurationValue) TargetUnit() string {
+	return value.Unit
+}
+
+// Convert converts duration to given unit, Go duration string is used by default
+func (value *DurationValue) Convert(unit string) (interface{}, error) {
+	if unit == "" || strings.EqualFold(unit, durationUnitString) {
+		return value.Duration.String(), nil
+	}
+	_, factor, ok := findUnit(durationUnits, durationUnitAliases, unit)
+	if !ok {
+		return nil, fmt.Errorf("unknown duration unit `%s`, supported units are %s", unit, unitNames(durationUnits, durationUnitString))
+	}
+	return convertToUnit(float64(value.Duration), factor), nil
+}
+
+// durationHandler handles DURATION values, accepting human input (e.g. `1h30m`, `2d`, numbers are treated as seconds)
+type durationHandler struct{}
+
+// Decode converts raw value into duration
+func (handler *durationHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	duration, err := decodeDuration(raw)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = (&DurationValue{}).Convert(context.Value.Unit); err != nil {
+		return nil, err
+	}
+	return duration, nil
+}
+
+// Validate checks duration (in seconds) against `min` and `max` constraints
+func (handler *durationHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return validateNumber(context.Value, decoded.(time.Duration).Seconds())
+}
+
+// Render wraps duration into value which is converted to the target unit when written
+func (handler *durationHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	return &DurationValue{
+		Duration: decoded.(time.Duration),
+		Unit:     context.Value.Unit,
+	}, nil
+}
+
+// SupportsDelay reports that duration values can be published with delay
+func (handler *durationHandler) SupportsDelay() bool {
+	return true
+}
+
+// decodeDuration converts received value to duration
+func decodeDuration(value interface{}) (time.Duration, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		return time.Duration(typedValue * float64(time.Second)), nil
+	case string:
+		text := strings.ReplaceAll(strings.TrimSpace(typedValue), " ", "")
+		if seconds, err := strconv.ParseFloat(text, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+		var days time.Duration
+		if matches := durationDaysPattern.FindStringSubmatch(text); matches != nil {
+			count, _ := strconv.ParseFloat(matches[1], 64)
+			days, text = time.Duration(count*float64(24*time.Hour)), matches[2]
+			if text == "" {
+				return days, nil
+			}
+		}
+		if duration, err := time.ParseDuration(text); err == nil {
+			return days + duration, nil
+		}
+	}
+	return 0, fmt.Errorf("`%v` (%T) is not a valid duration value", value, value)
+}
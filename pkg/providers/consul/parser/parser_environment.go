@@ -0,0 +1,21 @@
+package parser
+
+import "strings"
+
+// applyEnvironmentOverride replaces value with the one defined for environment of current agent (if any)
+func (parser *Parser) applyEnvironmentOverride(value *ConsulValue) {
+	if len(value.Environments) == 0 {
+		return
+	}
+	environment := strings.TrimSpace(parser.config.Environment)
+	if override, ok := value.Environments[environment]; ok {
+		value.Value = override
+		return
+	}
+	for name, override := range value.Environments {
+		if strings.EqualFold(name, environment) {
+			value.Value = override
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package parser
+
+import "testing"
+
+func TestEnvironmentOverrideReplacesValue(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Environment = "production"
+	configuration := generate(parser, pair("app/host", `{"type":"string","value":"localhost","environments":{"production":"db.internal"}}`))
+	if value := configuration["CONSUL_APP_HOST"]; value != "db.internal" {
+		t.Fatalf("expected environment override to be used, got %#v", value)
+	}
+}
+
+func TestEnvironmentOverrideIsMatchedCaseInsensitive(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Environment = " Production "
+	configuration := generate(parser, pair("app/host", `{"type":"string","value":"localhost","environments":{"PRODUCTION":"db.internal"}}`))
+	if value := configuration["CONSUL_APP_HOST"]; value != "db.internal" {
+		t.Fatalf("expected environment override to be used, got %#v", value)
+	}
+}
+
+func TestEnvironmentOverrideOfOtherEnvironmentIsIgnored(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Environment = "staging"
+	configuration := generate(parser, pair("app/host", `{"type":"string","value":"localhost","environments":{"production":"db.internal"}}`))
+	if value := configuration["CONSUL_APP_HOST"]; value != "localhost" {
+		t.Fatalf("expected default value to be used, got %#v", value)
+	}
+}
+
+func TestEnvironmentOverrideIsValidated(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Environment = "production"
+	configuration := generate(parser, pair("app/port", `{"type":"number","value":80,"environments":{"production":"not a number"}}`))
+	if _, found := configuration["CONSUL_APP_PORT"]; found {
+		t.Fatalf("expected invalid environment override to be rejected")
+	}
+}
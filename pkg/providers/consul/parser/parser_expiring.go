@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leads-su/logger"
+)
+
+// ExpiringValue describes value which should be reverted once its expiration time is reached
+type ExpiringValue struct {
+	Path      string
+	Value     *ConsulValue
+	ExpiresOn time.Time
+}
+
+// ScheduledExpiration describes value which is waiting for its expiration time
+type ScheduledExpiration struct {
+	Key         string    `json:"key"`
+	Path        string    `json:"path"`
+	ExpiresOn   time.Time `json:"expires_on"`
+	HasFallback bool      `json:"has_fallback"`
+}
+
+// newExpiringValue creates new instance of expiring value
+func (parser *Parser) newExpiringValue(path string, value *ConsulValue) (*ExpiringValue, error) {
+	expires, ok := value.Expires.(string)
+	if !ok {
+		return nil, fmt.Errorf("expiration time must be a string, got %T", value.Expires)
+	}
+	expiresOn, err := time.Parse(time.RFC3339, expires)
+	if err != nil {
+		return nil, err
+	}
+	return &ExpiringValue{
+		Path:      path,
+		Value:     value,
+		ExpiresOn: expiresOn,
+	}, nil
+}
+
+// processExpiration registers expiration time for the value, if one was provided
+func (parser *Parser) processExpiration(path, key string, value *ConsulValue) {
+	if value.Expires == nil {
+		return
+	}
+	expiringValue, err := parser.newExpiringValue(path, value)
+	if err != nil {
+		logger.Errorf("consul:parser:expiring", "failed to set expiration for `%s` - %s", path, err.Error())
+		return
+	}
+	parser.setExpiringDataValue(key, expiringValue)
+}
+
+// hasExpired checks whether value has reached its expiration time
+func (ev *ExpiringValue) hasExpired() bool {
+	return time.Now().UTC().Unix() >= ev.ExpiresOn.UTC().Unix()
+}
+
+// revertExpiredData replaces expired values with their fallback value or removes them from live data map
+func (parser *Parser) revertExpiredData() {
+	for key, expiringValue := range parser.expiringData {
+		if !expiringValue.hasExpired() {
+			continue
+		}
+
+		var message string
+		failed := false
+		if expiringValue.Value.Fallback != nil {
+			fallbackValue := *expiringValue.Value
+			fallbackValue.Value = fallbackValue.Fallback
+			fallbackValue.Fallback = nil
+			fallbackValue.Delayed = nil
+			fallbackValue.Expires = nil
+			if err := parser.processValue(expiringValue.Path, key, &fallbackValue); err != nil {
+				// Expired value must not stay published when its fallback cannot be used
+				parser.removeExpiredValue(key, expiringValue.Path)
+				message = fmt.Sprintf("value of `%s` expired at %s, failed to revert it to fallback value, variable was removed - %s", expiringValue.Path, expiringValue.ExpiresOn.Format(time.RFC3339), err.Error())
+				failed = true
+			} else {
+				message = fmt.Sprintf("value of `%s` expired at %s, reverted to fallback value", expiringValue.Path, expiringValue.ExpiresOn.Format(time.RFC3339))
+			}
+		} else {
+			parser.removeExpiredValue(key, expiringValue.Path)
+			message = fmt.Sprintf("value of `%s` expired at %s, variable was removed", expiringValue.Path, expiringValue.ExpiresOn.Format(time.RFC3339))
+		}
+		parser.removeDelayedDataValue(key)
+		parser.removeExpiringDataValue(key)
+
+		if parser.revertedData[key] != expiringValue.ExpiresOn {
+			parser.setRevertedDataValue(key, expiringValue.ExpiresOn)
+			if failed {
+				logger.Error("consul:parser:expiring", message)
+				parser.sendErrorNotification(message)
+			} else {
+				logger.Info("consul:parser:expiring", message)
+				parser.sendInfoNotification(message)
+			}
+		}
+	}
+}
+
+// removeExpiredValue removes expired value and values derived from it from live data map
+func (parser *Parser) removeExpiredValue(key, path string) {
+	parser.removeReferenceValue(key)
+	parser.removeDataValue(key)
+	parser.removeTemplateDataValue(key)
+	for _, derivedKey := range parser.referenceStorage.DerivedKeys(path) {
+		parser.removeDataValue(derivedKey)
+		parser.removeTemplateDataValue(derivedKey)
+	}
+}
+
+// NextExpiration returns the closest time at which one of expiring values should be reverted
+func (parser *Parser) NextExpiration() (time.Time, bool) {
+	parser.RLock()
+	defer parser.RUnlock()
+	var next time.Time
+	for _, expiringValue := range parser.expiringData {
+		if next.IsZero() || expiringValue.ExpiresOn.Before(next) {
+			next = expiringValue.ExpiresOn
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// ScheduledExpirations returns list of values which are waiting to be reverted
+func (parser *Parser) ScheduledExpirations() []ScheduledExpiration {
+	parser.RLock()
+	expirations := make([]ScheduledExpiration, 0, len(parser.expiringData))
+	for key, expiringValue := range parser.expiringData {
+		expirations = append(expirations, ScheduledExpiration{
+			Key:         key,
+			Path:        expiringValue.Path,
+			ExpiresOn:   expiringValue.ExpiresOn,
+			HasFallback: expiringValue.Value.Fallback != nil,
+		})
+	}
+	parser.RUnlock()
+
+	sort.Slice(expirations, func(i, j int) bool {
+		return expirations[i].ExpiresOn.Before(expirations[j].ExpiresOn)
+	})
+	return expirations
+}
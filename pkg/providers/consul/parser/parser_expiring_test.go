@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpiredValueIsRevertedToFallback(t *testing.T) {
+	parser := newTestParser(t)
+	expires := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	configuration := generate(parser, pair("app/port", `{"type":"number","value":8080,"fallback":80,"expires":"`+expires+`"}`))
+
+	if value := configuration["CONSUL_APP_PORT"]; value != float64(80) {
+		t.Fatalf("expected fallback value 80, got %#v", value)
+	}
+}
+
+func TestExpiredValueWithoutFallbackIsRemoved(t *testing.T) {
+	parser := newTestParser(t)
+	expires := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	configuration := generate(parser, pair("app/port", `{"type":"number","value":8080,"expires":"`+expires+`"}`))
+
+	if value, found := configuration["CONSUL_APP_PORT"]; found {
+		t.Fatalf("expected expired value to be removed, got %#v", value)
+	}
+}
+
+// failingRenderHandler is a value handler which fails to render `broken` values
+type failingRenderHandler struct{}
+
+func (handler *failingRenderHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	return raw, nil
+}
+
+func (handler *failingRenderHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return nil
+}
+
+func (handler *failingRenderHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	if decoded == "broken" {
+		return nil, fmt.Errorf("value cannot be rendered")
+	}
+	return decoded, nil
+}
+
+func TestExpiredValueWithFailingFallbackIsRemoved(t *testing.T) {
+	RegisterValueHandler("test-failing-render", &failingRenderHandler{})
+	parser := newTestParser(t)
+	expires := time.Now().Add(time.Second).UTC().Format(time.RFC3339)
+	configuration := generate(parser, pair("app/mode", `{"type":"test-failing-render","value":"active","fallback":"broken","expires":"`+expires+`"}`))
+	if value := configuration["CONSUL_APP_MODE"]; value != "active" {
+		t.Fatalf("expected value `active` before expiration, got %#v", value)
+	}
+
+	time.Sleep(2 * time.Second)
+	configuration = parser.GenerateConfiguration()
+	if value, found := configuration["CONSUL_APP_MODE"]; found {
+		t.Fatalf("expected value which fallback failed to be removed, got %#v", value)
+	}
+}
+
+func TestValueIsPublishedUntilItExpires(t *testing.T) {
+	parser := newTestParser(t)
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	configuration := generate(parser, pair("app/port", `{"type":"number","value":8080,"fallback":80,"expires":"`+expires+`"}`))
+
+	if value := configuration["CONSUL_APP_PORT"]; value != float64(8080) {
+		t.Fatalf("expected value 8080 before expiration, got %#v", value)
+	}
+	if _, ok := parser.NextExpiration(); !ok {
+		t.Fatalf("expected expiration to be scheduled")
+	}
+}
@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileValue describes file which contents should be written to disk as is
+type FileValue struct {
+	// Path is a target path relative to the `consul.write_to` directory
+	Path string
+
+	// Content is decoded file contents
+	Content []byte
+
+	// Mode is a permission mode which will be applied to the file
+	Mode os.FileMode
+
+	// Owner is an owner of the file in `user`, `user:group` or `:group` format
+	Owner string
+}
+
+// fileHandler handles FILE values, which contents are written to disk as is
+type fileHandler struct{}
+
+// Decode converts raw value into file value
+func (handler *fileHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	return newFileValue(context.Path, context.Value, raw)
+}
+
+// Validate does nothing, as file value is checked while it is decoded
+func (handler *fileHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return nil
+}
+
+// Render returns file value as is
+func (handler *fileHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	return decoded, nil
+}
+
+// SupportsDelay reports that file values can be published with delay
+func (handler *fileHandler) SupportsDelay() bool {
+	return true
+}
+
+// newFileValue creates new instance of file value from decoded Consul value
+func newFileValue(path string, value *ConsulValue, raw interface{}) (*FileValue, error) {
+	rawContent, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("file contents must be a string, got %T", raw)
+	}
+
+	var content []byte
+	switch strings.ToLower(value.Encoding) {
+	case "", "plain":
+		content = []byte(rawContent)
+	case "base64":
+		decodedContent, err := base64.StdEncoding.DecodeString(rawContent)
+		if err != nil {
+			return nil, err
+		}
+		content = decodedContent
+	default:
+		return nil, fmt.Errorf("unknown file encoding - `%s`", value.Encoding)
+	}
+
+	targetPath := strings.TrimSpace(value.Path)
+	if targetPath == "" {
+		targetPath = path
+	}
+	targetPath = filepath.Clean(targetPath)
+	if filepath.IsAbs(targetPath) || targetPath == ".." || strings.HasPrefix(targetPath, ".."+string(os.PathSeparator)) {
+		return nil, fmt.Errorf("file path `%s` must be relative to the configuration directory", value.Path)
+	}
+
+	mode := os.FileMode(0644)
+	if value.Mode != "" {
+		parsedMode, err := strconv.ParseUint(value.Mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file mode `%s` - %s", value.Mode, err.Error())
+		}
+		mode = os.FileMode(parsedMode)
+	}
+
+	owner := strings.TrimSpace(value.Owner)
+	if group := strings.TrimSpace(value.Group); group != "" && !strings.Contains(owner, ":") {
+		owner += ":" + group
+	}
+
+	return &FileValue{
+		Path:    targetPath,
+		Content: content,
+		Mode:    mode,
+		Owner:   owner,
+	}, nil
+}
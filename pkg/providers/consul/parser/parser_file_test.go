@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFileValueIsDecoded(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/certificate", `{"type":"file","path":"certs/tls.pem","value":"c2VjcmV0","encoding":"base64","mode":"0600","owner":"www-data","group":"ssl"}`),
+		pair("app/motd", `{"type":"file","value":"hello"}`),
+	)
+
+	certificate, ok := configuration["CONSUL_APP_CERTIFICATE"].(*FileValue)
+	if !ok {
+		t.Fatalf("expected file value, got %#v", configuration["CONSUL_APP_CERTIFICATE"])
+	}
+	if certificate.Path != "certs/tls.pem" || string(certificate.Content) != "secret" || certificate.Mode != os.FileMode(0600) || certificate.Owner != "www-data:ssl" {
+		t.Fatalf("unexpected file value %#v", certificate)
+	}
+
+	motd, ok := configuration["CONSUL_APP_MOTD"].(*FileValue)
+	if !ok || motd.Path != "app/motd" || motd.Mode != os.FileMode(0644) {
+		t.Fatalf("expected file value written to its Consul path with default mode, got %#v", configuration["CONSUL_APP_MOTD"])
+	}
+}
+
+func TestInvalidFileValuesAreRejected(t *testing.T) {
+	values := map[string]string{
+		"absolute path": `{"type":"file","path":"/etc/passwd","value":"x"}`,
+		"parent path":   `{"type":"file","path":"../outside","value":"x"}`,
+		"bad encoding":  `{"type":"file","value":"x","encoding":"hex"}`,
+		"bad base64":    `{"type":"file","value":"%%%","encoding":"base64"}`,
+		"bad mode":      `{"type":"file","value":"x","mode":"rw"}`,
+		"non-string":    `{"type":"file","value":42}`,
+	}
+	for name, value := range values {
+		t.Run(name, func(t *testing.T) {
+			parser := newTestParser(t)
+			configuration := generate(parser, pair("app/file", value))
+			if _, found := configuration["CONSUL_APP_FILE"]; found {
+				t.Fatalf("expected invalid file value not to be published")
+			}
+		})
+	}
+}
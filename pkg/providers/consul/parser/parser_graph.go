@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"sort"
+	"time"
+)
+
+// KeySource describes Consul key as it was received in the last snapshot
+type KeySource struct {
+	Type        string
+	ModifyIndex uint64
+}
+
+// VariableProvenance describes where the value of a single variable comes from and what depends on it
+type VariableProvenance struct {
+	Key            string     `json:"key"`
+	Variable       string     `json:"variable"`
+	Path           string     `json:"path"`
+	Source         string     `json:"source"`
+	Type           string     `json:"type"`
+	ModifyIndex    uint64     `json:"modify_index"`
+	ReferenceChain []string   `json:"reference_chain,omitempty"`
+	Modifiers      []string   `json:"modifiers,omitempty"`
+	Dependencies   []string   `json:"dependencies,omitempty"`
+	Dependents     []string   `json:"dependents,omitempty"`
+	File           string     `json:"file,omitempty"`
+	Published      bool       `json:"published"`
+	PublishOn      *time.Time `json:"publish_on,omitempty"`
+	ExpiresOn      *time.Time `json:"expires_on,omitempty"`
+	Unresolved     string     `json:"unresolved,omitempty"`
+}
+
+// SetFileResolver sets function used to find out which file the variable is written to
+func (parser *Parser) SetFileResolver(resolver func(key string) string) {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.fileResolver = resolver
+}
+
+// buildDependencyGraph collects provenance of every key present in current snapshot
+func (parser *Parser) buildDependencyGraph(unresolved []UnresolvedReference) {
+	unresolvedReasons := make(map[string]string)
+	for _, reference := range unresolved {
+		unresolvedReasons[reference.Key] = reference.Reason
+	}
+
+	parser.RLock()
+	graph := make(map[string]*VariableProvenance, len(parser.snapshotKeys))
+	for key, path := range parser.snapshotKeys {
+		graph[key] = parser.describeProvenance(key, path)
+		graph[key].Unresolved = unresolvedReasons[key]
+	}
+	resolver := parser.fileResolver
+	parser.RUnlock()
+
+	for key, provenance := range graph {
+		for _, dependency := range provenance.Dependencies {
+			dependencyKey := parser.formatKey(dependency)
+			if dependent, ok := graph[dependencyKey]; ok {
+				dependent.Dependents = append(dependent.Dependents, provenance.Path)
+			}
+		}
+		if provenance.File == "" && provenance.Published && resolver != nil {
+			graph[key].File = resolver(key)
+		}
+	}
+	for _, provenance := range graph {
+		sort.Strings(provenance.Dependents)
+	}
+
+	parser.Lock()
+	parser.dependencyGraph = graph
+	parser.Unlock()
+}
+
+// describeProvenance creates provenance of a single key, parser must be locked for reading
+func (parser *Parser) describeProvenance(key, path string) *VariableProvenance {
+	source, err := parser.referenceStorage.GetSource(key)
+	if err != nil {
+		source = path
+	}
+	provenance := &VariableProvenance{
+		Key:      key,
+		Variable: parser.VariableName(key),
+		Path:     path,
+		Source:   source,
+	}
+	if keySource, ok := parser.keySources[source]; ok {
+		provenance.Type = keySource.Type
+		provenance.ModifyIndex = keySource.ModifyIndex
+	}
+
+	value, published := parser.liveData[key]
+	provenance.Published = published
+	if fileValue, ok := value.(*FileValue); ok {
+		provenance.File = fileValue.Path
+	}
+
+	if target, isReference := parser.referenceMap[key]; isReference {
+		provenance.Dependencies = []string{parser.describeKey(target)}
+		for _, modifier := range parser.referenceModifiers[key] {
+			expression := modifier.Name
+			if referenceModifierArguments[modifier.Name] {
+				expression += ":" + modifier.Argument
+			}
+			provenance.Modifiers = append(provenance.Modifiers, expression)
+		}
+		provenance.ReferenceChain = parser.describeReferenceChain(key)
+	}
+	if template, isTemplate := parser.templateData[key]; isTemplate {
+		seen := make(map[string]bool)
+		for _, placeholder := range interpolationPattern.FindAllString(template, -1) {
+			dependency, _, _ := parser.parsePlaceholder(placeholder)
+			if !seen[dependency] {
+				seen[dependency] = true
+				provenance.Dependencies = append(provenance.Dependencies, dependency)
+			}
+		}
+	}
+
+	if delayedPublisher, ok := parser.delayedData[key]; ok {
+		publishOn := delayedPublisher.PublishOn
+		provenance.PublishOn = &publishOn
+	}
+	if expiringValue, ok := parser.expiringData[key]; ok {
+		expiresOn := expiringValue.ExpiresOn
+		provenance.ExpiresOn = &expiresOn
+	}
+	return provenance
+}
+
+// describeReferenceChain returns Consul paths of every key reference goes through, ending with its final target
+func (parser *Parser) describeReferenceChain(key string) []string {
+	chain := []string{parser.describeKey(key)}
+	visited := map[string]bool{key: true}
+	target := parser.referenceMap[key]
+	for {
+		chain = append(chain, parser.describeKey(target))
+		next, isReference := parser.referenceMap[target]
+		if !isReference || visited[target] {
+			return chain
+		}
+		visited[target] = true
+		target = next
+	}
+}
+
+// DependencyGraph returns provenance of every key present in last generated configuration, sorted by Consul path
+func (parser *Parser) DependencyGraph() []*VariableProvenance {
+	parser.RLock()
+	defer parser.RUnlock()
+	graph := make([]*VariableProvenance, 0, len(parser.dependencyGraph))
+	for _, provenance := range parser.dependencyGraph {
+		graph = append(graph, provenance)
+	}
+	sort.Slice(graph, func(i, j int) bool {
+		return graph[i].Path < graph[j].Path
+	})
+	return graph
+}
+
+// Explain returns provenance of a single key, which can be referred to by its Consul path, internal key or variable name
+func (parser *Parser) Explain(pathOrKey string) (*VariableProvenance, bool) {
+	parser.RLock()
+	defer parser.RUnlock()
+	if provenance, ok := parser.dependencyGraph[pathOrKey]; ok {
+		return provenance, true
+	}
+	for _, provenance := range parser.dependencyGraph {
+		if provenance.Path == pathOrKey || provenance.Variable == pathOrKey {
+			return provenance, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDependencyGraphDescribesReferencesAndTemplates(t *testing.T) {
+	parser := newTestParser(t)
+	parser.SetFileResolver(func(key string) string {
+		return "resolved/" + key
+	})
+	generate(parser,
+		pair("shared/host", `{"type":"string","value":"db"}`),
+		pair("app/host", `{"type":"reference","value":"shared/host","modifiers":["upper"]}`),
+		pair("worker/host", `{"type":"reference","value":"app/host"}`),
+		pair("app/dsn", `{"type":"string","value":"postgres://${app/host}:${app/port}/${app/host}"}`),
+		pair("app/port", `{"type":"number","value":5432}`),
+	)
+
+	graph := parser.DependencyGraph()
+	paths := make([]string, 0, len(graph))
+	for _, provenance := range graph {
+		paths = append(paths, provenance.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"app/dsn", "app/host", "app/port", "shared/host", "worker/host"}) {
+		t.Fatalf("expected graph sorted by path, got %v", paths)
+	}
+
+	worker, ok := parser.Explain("worker/host")
+	if !ok {
+		t.Fatalf("expected `worker/host` to be explained")
+	}
+	if !reflect.DeepEqual(worker.ReferenceChain, []string{"worker/host", "app/host", "shared/host"}) {
+		t.Fatalf("unexpected reference chain %v", worker.ReferenceChain)
+	}
+	if worker.Type != "reference" || !worker.Published || worker.File != "resolved/CONSUL_WORKER_HOST" {
+		t.Fatalf("unexpected provenance %#v", worker)
+	}
+
+	host, _ := parser.Explain("CONSUL_APP_HOST")
+	if !reflect.DeepEqual(host.Modifiers, []string{"upper"}) || !reflect.DeepEqual(host.Dependencies, []string{"shared/host"}) {
+		t.Fatalf("unexpected provenance %#v", host)
+	}
+	if !reflect.DeepEqual(host.Dependents, []string{"app/dsn", "worker/host"}) {
+		t.Fatalf("expected dependents of `app/host`, got %v", host.Dependents)
+	}
+
+	dsn, _ := parser.Explain("CONSUL_APP_DSN")
+	if !reflect.DeepEqual(dsn.Dependencies, []string{"app/host", "app/port"}) {
+		t.Fatalf("expected template dependencies, got %v", dsn.Dependencies)
+	}
+}
+
+func TestExplainDescribesScheduledAndUnresolvedValues(t *testing.T) {
+	parser := newTestParser(t)
+	publishOn := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	generate(parser,
+		pair("app/mode", `{"type":"string","value":"new","delayed":"`+publishOn.Format(time.RFC3339)+`"}`),
+		pair("app/host", `{"type":"reference","value":"shared/missing"}`),
+	)
+
+	mode, ok := parser.Explain("app/mode")
+	if !ok || mode.Published || mode.PublishOn == nil || !mode.PublishOn.Equal(publishOn) {
+		t.Fatalf("expected delayed value to be described, got %#v", mode)
+	}
+	host, ok := parser.Explain("CONSUL_APP_HOST")
+	if !ok || host.Published || host.Unresolved == "" {
+		t.Fatalf("expected unresolved reference to be described, got %#v", host)
+	}
+	if _, ok := parser.Explain("app/unknown"); ok {
+		t.Fatalf("expected unknown key not to be explained")
+	}
+}
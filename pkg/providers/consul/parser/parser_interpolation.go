@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/leads-su/logger"
+)
+
+// interpolationPattern matches `${path/to/key}` and `${path/to/key:-fallback}` placeholders
+var interpolationPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// InterpolatedString describes string value with placeholders which are resolved on configuration generation
+type InterpolatedString struct {
+	Template string
+
+	// Constraints contains constraints rendered value is checked against, they are not set for derived values
+	Constraints *ConsulValue
+}
+
+// isInterpolated checks whether string value contains placeholders which should be resolved
+func isInterpolated(value string) bool {
+	return interpolationPattern.MatchString(value)
+}
+
+// renderTemplates resolves placeholders of all interpolated values and appends them to live data map
+func (parser *Parser) renderTemplates() []UnresolvedReference {
+	var unresolved []UnresolvedReference
+	rendered := make(map[string]string)
+	rejected := make(map[string]RejectedValue)
+	for key, template := range parser.templateData {
+		value, err := parser.renderTemplate(key, rendered, []string{})
+		var constraintErr *constraintError
+		if errors.As(err, &constraintErr) && constraintErr.key == key {
+			// Rendered value which violates constraints is rejected, last good value is kept
+			rejected[key] = parser.newRenderRejection(key, constraintErr)
+			continue
+		}
+		if err != nil {
+			logger.Errorf("consul:parser:interpolation", "failed to render value for `%s` - %s", key, err.Error())
+			unresolved = append(unresolved, parser.newUnresolvedReference(key, template, err.Error()))
+			parser.removeDataValue(key)
+			continue
+		}
+		parser.setDataValue(key, value)
+	}
+	parser.reportRenderRejections(rejected)
+	return unresolved
+}
+
+// renderTemplate resolves placeholders of a single interpolated value, rendering nested interpolated values on the way
+func (parser *Parser) renderTemplate(key string, rendered map[string]string, chain []string) (string, error) {
+	if value, ok := rendered[key]; ok {
+		return value, nil
+	}
+	for _, visited := range chain {
+		if visited == key {
+			return "", fmt.Errorf("circular interpolation detected - %s", strings.Join(append(chain, key), " -> "))
+		}
+	}
+	chain = append(chain, key)
+
+	var renderError error
+	value := interpolationPattern.ReplaceAllStringFunc(parser.templateData[key], func(placeholder string) string {
+		if renderError != nil {
+			return placeholder
+		}
+		path, fallback, hasFallback := parser.parsePlaceholder(placeholder)
+		resolved, err := parser.resolvePlaceholder(parser.formatKey(path), rendered, chain)
+		if err != nil {
+			if hasFallback {
+				return fallback
+			}
+			renderError = fmt.Errorf("unable to resolve `%s` - %s", path, err.Error())
+			return placeholder
+		}
+		return resolved
+	})
+	if renderError != nil {
+		return "", renderError
+	}
+	if constraints, ok := parser.templateConstraints[key]; ok {
+		if err := validateRenderedString(constraints, value); err != nil {
+			return "", &constraintError{key: key, value: value, err: err}
+		}
+	}
+
+	rendered[key] = value
+	return value, nil
+}
+
+// resolvePlaceholder retrieves string representation of the value placeholder points to,
+// modifiers of references met on the way are applied to the value
+func (parser *Parser) resolvePlaceholder(target string, rendered map[string]string, chain []string) (string, error) {
+	var references []string
+	for {
+		reference, isReference := parser.referenceMap[target]
+		if !isReference {
+			break
+		}
+		for _, visited := range chain {
+			if visited == target {
+				return "", fmt.Errorf("circular interpolation detected - %s", strings.Join(append(chain, target), " -> "))
+			}
+		}
+		chain = append(chain, target)
+		references = append(references, target)
+		target = reference
+	}
+
+	var value interface{}
+	var found bool
+	if _, isTemplate := parser.templateData[target]; isTemplate {
+		renderedValue, err := parser.renderTemplate(target, rendered, chain)
+		if err != nil {
+			return "", err
+		}
+		value, found = renderedValue, true
+	} else {
+		parser.RLock()
+		value, found = parser.liveData[target]
+		parser.RUnlock()
+	}
+
+	value, found, err := parser.applyReferenceModifiers(references, value, found)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("value for `%s` does not exist", target)
+	}
+	return stringifyValue(value)
+}
+
+// parsePlaceholder splits placeholder into key path and fallback value
+func (parser *Parser) parsePlaceholder(placeholder string) (string, string, bool) {
+	expression := placeholder[2 : len(placeholder)-1]
+	path, fallback, hasFallback := expression, "", false
+	if index := strings.Index(expression, ":-"); index != -1 {
+		path, fallback, hasFallback = expression[:index], expression[index+2:], true
+	}
+	return strings.Trim(strings.TrimSpace(path), "/"), fallback, hasFallback
+}
+
+// stringifyValue converts live data value into a string which can be embedded into another value
+func stringifyValue(value interface{}) (string, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, nil
+	case bool:
+		return strconv.FormatBool(typedValue), nil
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), nil
+	case int, int8, int16, int32, int64:
+		return fmt.Sprintf("%d", typedValue), nil
+	case UnitValue:
+		converted, err := typedValue.Convert(typedValue.TargetUnit())
+		if err != nil {
+			return "", err
+		}
+		return stringifyValue(converted)
+	case []interface{}:
+		var items []string
+		for _, item := range typedValue {
+			items = append(items, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(items, "\n"), nil
+	default:
+		return "", fmt.Errorf("value of type %T cannot be interpolated", value)
+	}
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlaceholdersAreResolved(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/host", `{"type":"string","value":"db"}`),
+		pair("app/port", `{"type":"number","value":5432}`),
+		pair("app/ssl", `{"type":"boolean","value":true}`),
+		pair("app/dsn", `{"type":"string","value":"postgres://${app/host}:${ app/port }/main?ssl=${/app/ssl/}"}`),
+	)
+	if value := configuration["CONSUL_APP_DSN"]; value != "postgres://db:5432/main?ssl=true" {
+		t.Fatalf("unexpected rendered value %#v", value)
+	}
+}
+
+func TestNestedPlaceholdersAreResolved(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/domain", `{"type":"string","value":"example.com"}`),
+		pair("app/host", `{"type":"string","value":"api.${app/domain}"}`),
+		pair("app/url", `{"type":"string","value":"https://${app/host}/v1"}`),
+	)
+	if value := configuration["CONSUL_APP_URL"]; value != "https://api.example.com/v1" {
+		t.Fatalf("unexpected rendered value %#v", value)
+	}
+}
+
+func TestPlaceholderFallbackIsUsedForMissingKey(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/url", `{"type":"string","value":"http://${app/host:-localhost}"}`))
+	if value := configuration["CONSUL_APP_URL"]; value != "http://localhost" {
+		t.Fatalf("unexpected rendered value %#v", value)
+	}
+}
+
+func TestUnresolvedPlaceholderIsReported(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/url", `{"type":"string","value":"http://${app/host}"}`))
+	if _, found := configuration["CONSUL_APP_URL"]; found {
+		t.Fatalf("expected value with unresolved placeholder not to be published")
+	}
+	unresolved := parser.UnresolvedReferences()
+	if len(unresolved) != 1 || unresolved[0].Path != "app/url" {
+		t.Fatalf("expected unresolved placeholder to be reported, got %#v", unresolved)
+	}
+}
+
+func TestCircularInterpolationIsReported(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/a", `{"type":"string","value":"${app/b}"}`),
+		pair("app/b", `{"type":"string","value":"${app/a}"}`),
+	)
+	if len(configuration) != 0 {
+		t.Fatalf("expected circular values not to be published, got %#v", configuration)
+	}
+	unresolved := parser.UnresolvedReferences()
+	if len(unresolved) != 2 {
+		t.Fatalf("expected both values to be reported, got %#v", unresolved)
+	}
+	for _, reference := range unresolved {
+		if !strings.Contains(reference.Reason, "circular") {
+			t.Fatalf("expected circular interpolation to be reported, got %#v", reference)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ReferenceModifier describes single transformation applied to the referenced value
+type ReferenceModifier struct {
+	Name     string
+	Argument string
+}
+
+// referenceModifierArguments lists supported modifiers and whether they require an argument
+var referenceModifierArguments = map[string]bool{
+	"index":     true,
+	"join":      true,
+	"default":   true,
+	"upper":     false,
+	"lower":     false,
+	"base64":    false,
+	"unbase64":  false,
+	"urlescape": false,
+}
+
+// parseReferenceModifiers converts list of `name` or `name:argument` strings into modifiers
+func parseReferenceModifiers(raw []string) ([]*ReferenceModifier, error) {
+	var modifiers []*ReferenceModifier
+	for _, expression := range raw {
+		name, argument, hasArgument := expression, "", false
+		if index := strings.Index(expression, ":"); index != -1 {
+			name, argument, hasArgument = expression[:index], expression[index+1:], true
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		requiresArgument, supported := referenceModifierArguments[name]
+		if !supported {
+			return nil, fmt.Errorf("unsupported reference modifier `%s`", name)
+		}
+		if requiresArgument != hasArgument {
+			if requiresArgument {
+				return nil, fmt.Errorf("reference modifier `%s` requires an argument (`%s:<value>`)", name, name)
+			}
+			return nil, fmt.Errorf("reference modifier `%s` does not accept an argument", name)
+		}
+		if name == "index" {
+			if _, err := strconv.Atoi(argument); err != nil {
+				return nil, fmt.Errorf("reference modifier `index` requires an integer argument, got `%s`", argument)
+			}
+		}
+		modifiers = append(modifiers, &ReferenceModifier{Name: name, Argument: argument})
+	}
+	return modifiers, nil
+}
+
+// applyReferenceModifiers applies modifiers of every reference in the chain, starting from the one
+// closest to the target, so the value is transformed the same way it would be by resolving references one by one
+func (parser *Parser) applyReferenceModifiers(chain []string, value interface{}, found bool) (interface{}, bool, error) {
+	var err error
+	for index := len(chain) - 1; index >= 0; index-- {
+		for _, modifier := range parser.referenceModifiers[chain[index]] {
+			value, found, err = modifier.apply(value, found)
+			if err != nil {
+				return nil, false, fmt.Errorf("modifier `%s` of `%s` failed - %s", modifier.Name, parser.describeKey(chain[index]), err.Error())
+			}
+		}
+	}
+	return value, found, nil
+}
+
+// apply transforms value with modifier, missing values are only accepted by `default` modifier
+func (modifier *ReferenceModifier) apply(value interface{}, found bool) (interface{}, bool, error) {
+	if modifier.Name == "default" {
+		if !found || isEmptyValue(value) {
+			return modifier.Argument, true, nil
+		}
+		return value, found, nil
+	}
+	if !found {
+		return nil, false, nil
+	}
+
+	switch modifier.Name {
+	case "index":
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("value of type %T is not an array", value)
+		}
+		position, _ := strconv.Atoi(modifier.Argument)
+		if position < 0 {
+			position += len(items)
+		}
+		if position < 0 || position >= len(items) {
+			return nil, false, fmt.Errorf("index %s is out of range for array of %d item(s)", modifier.Argument, len(items))
+		}
+		return items[position], true, nil
+	case "join":
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("value of type %T is not an array", value)
+		}
+		var parts []string
+		for _, item := range items {
+			parts = append(parts, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(parts, modifier.Argument), true, nil
+	}
+
+	stringValue, err := stringifyValue(value)
+	if err != nil {
+		return nil, false, err
+	}
+	switch modifier.Name {
+	case "upper":
+		return strings.ToUpper(stringValue), true, nil
+	case "lower":
+		return strings.ToLower(stringValue), true, nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(stringValue)), true, nil
+	case "unbase64":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stringValue))
+		if err != nil {
+			return nil, false, fmt.Errorf("value is not valid base64 - %s", err.Error())
+		}
+		return string(decoded), true, nil
+	case "urlescape":
+		return url.QueryEscape(stringValue), true, nil
+	}
+	return nil, false, fmt.Errorf("unsupported reference modifier `%s`", modifier.Name)
+}
@@ -0,0 +1,72 @@
+package parser
+
+import "testing"
+
+func TestReferenceModifiersReshapeValue(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("shared/hosts", `{"type":"array","value":["a.internal","b.internal","c.internal"]}`),
+		pair("shared/password", `{"type":"string","value":"p@ss word"}`),
+		pair("shared/token", `{"type":"string","value":"c2VjcmV0"}`),
+		pair("app/hosts", `{"type":"reference","value":"shared/hosts","modifiers":["join:,"]}`),
+		pair("app/last", `{"type":"reference","value":"shared/hosts","modifiers":["index:-1","upper"]}`),
+		pair("app/password", `{"type":"reference","value":"shared/password","modifiers":["urlescape"]}`),
+		pair("app/token", `{"type":"reference","value":"shared/token","modifiers":["unbase64"]}`),
+		pair("app/missing", `{"type":"reference","value":"shared/missing","modifiers":["default:fallback"]}`),
+	)
+	expected := map[string]string{
+		"CONSUL_APP_HOSTS":    "a.internal,b.internal,c.internal",
+		"CONSUL_APP_LAST":     "C.INTERNAL",
+		"CONSUL_APP_PASSWORD": "p%40ss+word",
+		"CONSUL_APP_TOKEN":    "secret",
+		"CONSUL_APP_MISSING":  "fallback",
+	}
+	for key, expectedValue := range expected {
+		if value := configuration[key]; value != expectedValue {
+			t.Fatalf("expected `%s` to be `%s`, got %#v", key, expectedValue, value)
+		}
+	}
+}
+
+func TestReferenceModifiersAreAppliedAlongTheChain(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("shared/name", `{"type":"string","value":"Service"}`),
+		pair("app/name", `{"type":"reference","value":"shared/name","modifiers":["upper"]}`),
+		pair("worker/name", `{"type":"reference","value":"app/name","modifiers":["base64"]}`),
+	)
+	if value := configuration["CONSUL_WORKER_NAME"]; value != "U0VSVklDRQ==" {
+		t.Fatalf("expected modifiers of every reference to be applied, got %#v", value)
+	}
+}
+
+func TestInvalidReferenceModifiersAreRejected(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("shared/hosts", `{"type":"array","value":["a"]}`),
+		pair("app/unknown", `{"type":"reference","value":"shared/hosts","modifiers":["reverse"]}`),
+		pair("app/join", `{"type":"reference","value":"shared/hosts","modifiers":["join"]}`),
+		pair("app/upper", `{"type":"reference","value":"shared/hosts","modifiers":["upper:x"]}`),
+		pair("app/index", `{"type":"reference","value":"shared/hosts","modifiers":["index:first"]}`),
+	)
+	for _, key := range []string{"CONSUL_APP_UNKNOWN", "CONSUL_APP_JOIN", "CONSUL_APP_UPPER", "CONSUL_APP_INDEX"} {
+		if value, found := configuration[key]; found {
+			t.Fatalf("expected `%s` with invalid modifiers not to be published, got %#v", key, value)
+		}
+	}
+}
+
+func TestFailingReferenceModifierIsReported(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("shared/hosts", `{"type":"array","value":["a"]}`),
+		pair("app/host", `{"type":"reference","value":"shared/hosts","modifiers":["index:5"]}`),
+	)
+	if value, found := configuration["CONSUL_APP_HOST"]; found {
+		t.Fatalf("expected reference with failing modifier not to be published, got %#v", value)
+	}
+	unresolved := parser.UnresolvedReferences()
+	if len(unresolved) != 1 || unresolved[0].Path != "app/host" {
+		t.Fatalf("expected reference to be reported as unresolved, got %#v", unresolved)
+	}
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+// VariableName returns name under which key is written to configuration file, according to `consul.naming` settings
+func (parser *Parser) VariableName(key string) string {
+	path, err := parser.referenceStorage.Get(key)
+	if err != nil {
+		return key
+	}
+	return parser.formatVariableName(strings.Trim(path, "/"))
+}
+
+// formatVariableName converts Consul key path to variable name
+func (parser *Parser) formatVariableName(path string) string {
+	naming := parser.namingOptions()
+
+	if rule := parser.matchNamingRule(naming, path); rule != nil {
+		remainder := strings.Trim(strings.TrimPrefix(path, strings.Trim(rule.Path, "/")), "/")
+		if remainder == "" {
+			return rule.Name
+		}
+		return rule.Name + "_" + parser.normalizeVariableName(naming, remainder)
+	}
+
+	segments := strings.Split(path, "/")
+	if naming.StripSegments > 0 {
+		strip := naming.StripSegments
+		if strip >= len(segments) {
+			strip = len(segments) - 1
+		}
+		segments = segments[strip:]
+	}
+	return naming.Prefix + parser.normalizeVariableName(naming, strings.Join(segments, "/"))
+}
+
+// matchNamingRule returns rename rule with the longest path matching given key path
+func (parser *Parser) matchNamingRule(naming *consul.Naming, path string) *consul.NamingRule {
+	var matchedRule *consul.NamingRule
+	for _, rule := range naming.Rules {
+		if rule == nil {
+			continue
+		}
+		rulePath := strings.Trim(rule.Path, "/")
+		if rulePath == "" || (path != rulePath && !strings.HasPrefix(path, rulePath+"/")) {
+			continue
+		}
+		if matchedRule == nil || len(rulePath) > len(strings.Trim(matchedRule.Path, "/")) {
+			matchedRule = rule
+		}
+	}
+	return matchedRule
+}
+
+// normalizeVariableName makes path a valid variable name
+func (parser *Parser) normalizeVariableName(naming *consul.Naming, path string) string {
+	if !naming.PreserveCase {
+		path = strings.ToUpper(path)
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(path, "/", "_"), "-", "_")
+}
+
+// namingOptions returns configured naming options, falling back to the default `CONSUL_` prefix
+func (parser *Parser) namingOptions() *consul.Naming {
+	if parser.config == nil || parser.config.Consul == nil || parser.config.Consul.Naming == nil {
+		return &consul.Naming{Prefix: "CONSUL_"}
+	}
+	return parser.config.Consul.Naming
+}
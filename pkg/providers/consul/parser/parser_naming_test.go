@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+func TestVariableNameUsesDefaultPrefix(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/db-pool/max", `{"type":"number","value":10}`))
+	if name := parser.VariableName("CONSUL_APP_DB_POOL_MAX"); name != "CONSUL_APP_DB_POOL_MAX" {
+		t.Fatalf("expected default variable name, got `%s`", name)
+	}
+}
+
+func TestVariableNameWithPrefixAndStrippedSegments(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Consul.Naming = &consul.Naming{Prefix: "APP_", StripSegments: 2}
+	generate(parser,
+		pair("services/api/db/host", `{"type":"string","value":"localhost"}`),
+		pair("services/port", `{"type":"number","value":80}`),
+	)
+	if name := parser.VariableName("CONSUL_SERVICES_API_DB_HOST"); name != "APP_DB_HOST" {
+		t.Fatalf("expected stripped variable name, got `%s`", name)
+	}
+	if name := parser.VariableName("CONSUL_SERVICES_PORT"); name != "APP_PORT" {
+		t.Fatalf("expected last segment to be kept, got `%s`", name)
+	}
+}
+
+func TestVariableNameWithPreservedCase(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Consul.Naming = &consul.Naming{PreserveCase: true}
+	generate(parser, pair("app/dbHost", `{"type":"string","value":"localhost"}`))
+	if name := parser.VariableName("CONSUL_APP_DBHOST"); name != "app_dbHost" {
+		t.Fatalf("expected case to be preserved, got `%s`", name)
+	}
+}
+
+func TestVariableNameUsesLongestMatchingRule(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Consul.Naming = &consul.Naming{
+		Prefix: "CONSUL_",
+		Rules: []*consul.NamingRule{
+			{Path: "app", Name: "APPLICATION"},
+			{Path: "/app/db/", Name: "DATABASE"},
+		},
+	}
+	generate(parser,
+		pair("app/db", `{"type":"string","value":"main"}`),
+		pair("app/db/host", `{"type":"string","value":"localhost"}`),
+		pair("app/port", `{"type":"number","value":80}`),
+		pair("application/port", `{"type":"number","value":80}`),
+	)
+	expected := map[string]string{
+		"CONSUL_APP_DB":           "DATABASE",
+		"CONSUL_APP_DB_HOST":      "DATABASE_HOST",
+		"CONSUL_APP_PORT":         "APPLICATION_PORT",
+		"CONSUL_APPLICATION_PORT": "CONSUL_APPLICATION_PORT",
+	}
+	for key, expectedName := range expected {
+		if name := parser.VariableName(key); name != expectedName {
+			t.Fatalf("expected `%s` to be named `%s`, got `%s`", key, expectedName, name)
+		}
+	}
+}
+
+func TestVariableNameOfUnknownKeyIsKeyItself(t *testing.T) {
+	parser := newTestParser(t)
+	if name := parser.VariableName("CONSUL_UNKNOWN"); name != "CONSUL_UNKNOWN" {
+		t.Fatalf("expected key to be returned, got `%s`", name)
+	}
+}
@@ -1,17 +1,54 @@
 package parser
 
-import "github.com/leads-su/logger"
-
-// processNumber processes NUMBER and appends it to live data map
-func (parser *Parser) processNumber(key string, value interface{}, delayed interface{}) {
-	if parser.shouldDelay(delayed) {
-		delayedPublisher, err := parser.newDelayedPublisher(value, delayed.(string))
-		if err != nil {
-			logger.Errorf("consul:parser:number", "failed to set delayed publisher for `%s` - %s", key, err.Error())
-		} else {
-			parser.setDelayedDataValue(key, delayedPublisher)
+import (
+	"fmt"
+	"strconv"
+)
+
+// numberHandler handles NUMBER values
+type numberHandler struct{}
+
+// Decode converts raw value into number, allowing numeric strings
+func (handler *numberHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	return decodeNumber(raw)
+}
+
+// Validate checks number against `min` and `max` constraints
+func (handler *numberHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return validateNumber(context.Value, decoded.(float64))
+}
+
+// Render returns number as is
+func (handler *numberHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	return decoded, nil
+}
+
+// SupportsDelay reports that number values can be published with delay
+func (handler *numberHandler) SupportsDelay() bool {
+	return true
+}
+
+// decodeNumber converts received value to number, allowing numeric strings
+func decodeNumber(value interface{}) (float64, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		return typedValue, nil
+	case string:
+		number, err := strconv.ParseFloat(typedValue, 64)
+		if err == nil {
+			return number, nil
 		}
-	} else {
-		parser.setDataValue(key, value)
 	}
+	return 0, fmt.Errorf("`%v` (%T) is not a valid number value", value, value)
+}
+
+// validateNumber checks number against `min` and `max` constraints
+func validateNumber(value *ConsulValue, number float64) error {
+	if value.Min != nil && number < *value.Min {
+		return fmt.Errorf("`%v` is less than allowed minimum of %v", number, *value.Min)
+	}
+	if value.Max != nil && number > *value.Max {
+		return fmt.Errorf("`%v` is greater than allowed maximum of %v", number, *value.Max)
+	}
+	return nil
 }
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/leads-su/logger"
+)
+
+// DerivedValues describes values derived from a single Consul key, indexed by their path
+type DerivedValues map[string]interface{}
+
+// objectHandler handles OBJECT values, nested fields are flattened into separate keys
+type objectHandler struct{}
+
+// Decode converts raw value into object
+func (handler *objectHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("object value must be a JSON object, got %T", raw)
+	}
+	return values, nil
+}
+
+// Validate does nothing, as nested fields are not checked against constraints
+func (handler *objectHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return nil
+}
+
+// Render flattens nested fields into values indexed by their path
+func (handler *objectHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	derivedValues := make(DerivedValues)
+	handler.flatten(context.Path, decoded.(map[string]interface{}), derivedValues)
+	return derivedValues, nil
+}
+
+// SupportsDelay reports that object values can be published with delay
+func (handler *objectHandler) SupportsDelay() bool {
+	return true
+}
+
+// flatten appends every nested field of the object to derived values
+func (handler *objectHandler) flatten(path string, values map[string]interface{}, derivedValues DerivedValues) {
+	for name, value := range values {
+		nestedPath := fmt.Sprintf("%s/%s", path, name)
+
+		switch typedValue := value.(type) {
+		case map[string]interface{}:
+			handler.flatten(nestedPath, typedValue, derivedValues)
+		case []interface{}:
+			derivedValues[nestedPath] = typedValue
+		case string:
+			if isInterpolated(typedValue) {
+				derivedValues[nestedPath] = &InterpolatedString{Template: typedValue}
+			} else {
+				derivedValues[nestedPath] = typedValue
+			}
+		case bool, float64:
+			derivedValues[nestedPath] = typedValue
+		case nil:
+			logger.Warnf("consul:parser:object", "skipping empty value for `%s`", nestedPath)
+		default:
+			logger.Errorf("consul:parser:object", "unsupported value for `%s` - %v (%T)", nestedPath, value, value)
+		}
+	}
+}
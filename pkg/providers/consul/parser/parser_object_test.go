@@ -0,0 +1,48 @@
+package parser
+
+import "testing"
+
+func TestObjectIsFlattenedIntoKeys(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/db", `{"type":"object","value":{"host":"h","port":5432,"ssl":true,"replica":{"host":"r"},"hosts":["a","b"],"empty":null}}`))
+
+	expected := map[string]interface{}{
+		"CONSUL_APP_DB_HOST":         "h",
+		"CONSUL_APP_DB_PORT":         float64(5432),
+		"CONSUL_APP_DB_SSL":          true,
+		"CONSUL_APP_DB_REPLICA_HOST": "r",
+	}
+	for key, value := range expected {
+		if configuration[key] != value {
+			t.Fatalf("expected `%s` to be %#v, got %#v", key, value, configuration)
+		}
+	}
+	if hosts, ok := configuration["CONSUL_APP_DB_HOSTS"].([]interface{}); !ok || len(hosts) != 2 {
+		t.Fatalf("expected array field to be kept as array, got %#v", configuration["CONSUL_APP_DB_HOSTS"])
+	}
+	if _, found := configuration["CONSUL_APP_DB_EMPTY"]; found {
+		t.Fatalf("expected empty field to be skipped")
+	}
+	if _, found := configuration["CONSUL_APP_DB"]; found {
+		t.Fatalf("expected object key itself not to be published")
+	}
+}
+
+func TestObjectFieldsCanBeInterpolated(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/domain", `{"type":"string","value":"example.com"}`),
+		pair("app/db", `{"type":"object","value":{"host":"db.${app/domain}"}}`),
+	)
+	if value := configuration["CONSUL_APP_DB_HOST"]; value != "db.example.com" {
+		t.Fatalf("expected interpolated field, got %#v", value)
+	}
+}
+
+func TestNonObjectValueIsRejected(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/db", `{"type":"object","value":"host"}`))
+	if rejected := parser.RejectedValues(); len(rejected) != 1 {
+		t.Fatalf("expected non-object value to be rejected, got %#v", rejected)
+	}
+}
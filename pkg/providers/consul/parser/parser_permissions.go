@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FilePermissions describes mode and ownership value requests for the configuration file it is written to
+type FilePermissions struct {
+	// Mode is a permission mode of the file, zero if not set
+	Mode os.FileMode
+
+	// Owner is a name of the user who owns the file
+	Owner string
+
+	// Group is a name of the group which owns the file
+	Group string
+}
+
+// applyFilePermissions stores mode and ownership requested by the value, file values manage their own permissions
+func (parser *Parser) applyFilePermissions(path string, value *ConsulValue) error {
+	if value.Type == "file" || (value.Mode == "" && value.Owner == "" && value.Group == "") {
+		return nil
+	}
+
+	permissions := &FilePermissions{
+		Owner: strings.TrimSpace(value.Owner),
+		Group: strings.TrimSpace(value.Group),
+	}
+	if parts := strings.SplitN(permissions.Owner, ":", 2); len(parts) == 2 && permissions.Group == "" {
+		permissions.Owner, permissions.Group = parts[0], parts[1]
+	}
+	if value.Mode != "" {
+		parsedMode, err := strconv.ParseUint(value.Mode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid file mode `%s` - %s", value.Mode, err.Error())
+		}
+		permissions.Mode = os.FileMode(parsedMode)
+	}
+
+	parser.Lock()
+	defer parser.Unlock()
+	parser.filePermissions[path] = permissions
+	return nil
+}
+
+// resetFilePermissions removes all requested file permissions
+func (parser *Parser) resetFilePermissions() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.filePermissions = make(map[string]*FilePermissions)
+}
+
+// FilePermissions returns mode and ownership requested by the key under given Consul path
+func (parser *Parser) FilePermissions(path string) (*FilePermissions, bool) {
+	parser.RLock()
+	defer parser.RUnlock()
+	permissions, ok := parser.filePermissions[path]
+	return permissions, ok
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leads-su/logger"
+)
+
+// QuarantinedValue describes value which could not be decoded and was ignored
+type QuarantinedValue struct {
+	Key           string    `json:"key"`
+	Path          string    `json:"path"`
+	Reason        string    `json:"reason"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
+
+// isPlainValuePath checks whether key path matches one of `consul.plain_values` prefixes
+func (parser *Parser) isPlainValuePath(path string) bool {
+	if parser.config == nil || parser.config.Consul == nil {
+		return false
+	}
+	path = strings.Trim(path, "/")
+	for _, prefix := range parser.config.Consul.PlainValues {
+		prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// quarantineValue stores information about value which could not be decoded, previously published value is kept
+func (parser *Parser) quarantineValue(path, key string, err error) {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.quarantinedValues[key] = QuarantinedValue{
+		Key:           key,
+		Path:          path,
+		Reason:        err.Error(),
+		QuarantinedAt: time.Now().UTC(),
+	}
+}
+
+// reportQuarantinedValues logs values which were quarantined for the first time
+func (parser *Parser) reportQuarantinedValues(previous map[string]QuarantinedValue) {
+	parser.Lock()
+	defer parser.Unlock()
+	for key, quarantined := range parser.quarantinedValues {
+		if previousQuarantine, ok := previous[key]; ok && previousQuarantine.Reason == quarantined.Reason {
+			quarantined.QuarantinedAt = previousQuarantine.QuarantinedAt
+			parser.quarantinedValues[key] = quarantined
+			continue
+		}
+		logger.Errorf("consul:parser", "failed to decode value for `%s` key, value was quarantined - %s", quarantined.Path, quarantined.Reason)
+	}
+}
+
+// QuarantinedValues returns list of values which could not be decoded during last processing of Consul data
+func (parser *Parser) QuarantinedValues() []QuarantinedValue {
+	parser.RLock()
+	defer parser.RUnlock()
+	quarantined := make([]QuarantinedValue, 0, len(parser.quarantinedValues))
+	for _, value := range parser.quarantinedValues {
+		quarantined = append(quarantined, value)
+	}
+	sort.Slice(quarantined, func(i, j int) bool {
+		return quarantined[i].Path < quarantined[j].Path
+	})
+	return quarantined
+}
@@ -0,0 +1,57 @@
+package parser
+
+import "testing"
+
+func TestPlainValueIsPublishedAsLiteralString(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Consul.PlainValues = []string{"/legacy/"}
+	configuration := generate(parser,
+		pair("legacy/home", `${HOME}`),
+		pair("legacy/port", `8080`),
+	)
+	if value := configuration["CONSUL_LEGACY_HOME"]; value != "${HOME}" {
+		t.Fatalf("expected plain value to be published literally, got %#v", value)
+	}
+	if value := configuration["CONSUL_LEGACY_PORT"]; value != "8080" {
+		t.Fatalf("expected plain value to be published as string, got %#v", value)
+	}
+}
+
+func TestEnvelopedValueUnderPlainValuesPrefixIsDecoded(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Consul.PlainValues = []string{"legacy"}
+	configuration := generate(parser, pair("legacy/port", `{"type":"number","value":8080}`))
+	if value := configuration["CONSUL_LEGACY_PORT"]; value != float64(8080) {
+		t.Fatalf("expected enveloped value to be decoded, got %#v", value)
+	}
+}
+
+func TestValueWithoutEnvelopeIsQuarantined(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Consul.PlainValues = []string{"legacy"}
+	configuration := generate(parser, pair("legacyapp/port", `8080`))
+	if _, found := configuration["CONSUL_LEGACYAPP_PORT"]; found {
+		t.Fatalf("expected value outside of plain values prefix not to be published")
+	}
+	quarantined := parser.QuarantinedValues()
+	if len(quarantined) != 1 || quarantined[0].Path != "legacyapp/port" || quarantined[0].Reason == "" {
+		t.Fatalf("expected value to be quarantined, got %#v", quarantined)
+	}
+}
+
+func TestQuarantinedValueKeepsPreviousValue(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/port", `{"type":"number","value":8080}`))
+	configuration := generate(parser, pair("app/port", `{"type":`))
+	if value := configuration["CONSUL_APP_PORT"]; value != float64(8080) {
+		t.Fatalf("expected previous value to be kept, got %#v", value)
+	}
+	if quarantined := parser.QuarantinedValues(); len(quarantined) != 1 {
+		t.Fatalf("expected value to be quarantined, got %#v", quarantined)
+	}
+
+	generate(parser, pair("app/port", `{"type":"number","value":80}`))
+	if quarantined := parser.QuarantinedValues(); len(quarantined) != 0 {
+		t.Fatalf("expected quarantine to be lifted, got %#v", quarantined)
+	}
+}
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/leads-su/logger"
+)
+
+// ReferenceTarget describes Consul key path which reference points to
+type ReferenceTarget struct {
+	Path      string
+	Modifiers []*ReferenceModifier
+}
+
+// referenceHandler handles REFERENCE values, which are resolved to the value of another key
+type referenceHandler struct{}
+
+// Decode converts raw value into reference target
+func (handler *referenceHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	path, ok := raw.(string)
+	if !ok || strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("`%v` (%T) is not a valid reference", raw, raw)
+	}
+	modifiers, err := parseReferenceModifiers(context.Value.Modifiers)
+	if err != nil {
+		return nil, err
+	}
+	return &ReferenceTarget{
+		Path:      strings.Trim(strings.TrimSpace(path), "/"),
+		Modifiers: modifiers,
+	}, nil
+}
+
+// Validate does nothing, as reference target is checked while references are resolved
+func (handler *referenceHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return nil
+}
+
+// Render returns reference target as is
+func (handler *referenceHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	return decoded, nil
+}
+
+// UnresolvedReference describes reference which could not be resolved to a value
+type UnresolvedReference struct {
+	Key    string `json:"key"`
+	Path   string `json:"path"`
+	Target string `json:"target"`
+	Reason string `json:"reason"`
+}
+
+// resolveReferences resolves nested references to the value they are pointing to.
+// References forming a cycle (or pointing into one) as well as references to missing keys
+// are rejected and reported as unresolved instead of being written to configuration.
+func (parser *Parser) resolveReferences() []UnresolvedReference {
+	var unresolved []UnresolvedReference
+
+	keys := make([]string, 0, len(parser.referenceMap))
+	for key := range parser.referenceMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	reportedCycles := make(map[string]bool)
+	for _, key := range keys {
+		target, chain, cycle := parser.followReference(key)
+		if cycle != nil {
+			description := parser.describeChain(cycle)
+			if !reportedCycles[description] {
+				reportedCycles[description] = true
+				logger.Errorf("consul:parser", "circular reference detected, rejecting all keys in the cycle - %s", description)
+			}
+			reason := fmt.Sprintf("circular reference - %s", description)
+			if !parser.chainContains(cycle, key) {
+				reason = fmt.Sprintf("references a key which is part of circular reference - %s", description)
+			}
+			unresolved = append(unresolved, parser.newUnresolvedReference(key, parser.referenceMap[key], reason))
+			parser.removeDataValue(key)
+			continue
+		}
+
+		val, err := parser.getDataValue(key, target)
+		val, found, modifierErr := parser.applyReferenceModifiers(chain, val, err == nil)
+		if modifierErr != nil {
+			logger.Errorf("consul:parser", "failed to transform reference value - %s", modifierErr.Error())
+			unresolved = append(unresolved, parser.newUnresolvedReference(key, target, modifierErr.Error()))
+			parser.removeDataValue(key)
+			continue
+		}
+		if !found {
+			logger.Errorf("consul:parser", "failed to retrieve reference value - %s", err)
+			unresolved = append(unresolved, parser.newUnresolvedReference(key, target, "referenced key does not exist"))
+			parser.removeDataValue(key)
+			continue
+		}
+		parser.setDataValue(key, val)
+	}
+	return unresolved
+}
+
+// followReference walks reference chain starting at key and returns final target with references leading to it,
+// if chain loops back onto itself keys forming the cycle are returned instead
+func (parser *Parser) followReference(key string) (string, []string, []string) {
+	chain := []string{key}
+	positions := map[string]int{key: 0}
+	target := parser.referenceMap[key]
+
+	for {
+		if position, visited := positions[target]; visited {
+			return "", nil, append(chain[position:], target)
+		}
+		next, isReference := parser.referenceMap[target]
+		if !isReference {
+			return target, chain, nil
+		}
+		positions[target] = len(chain)
+		chain = append(chain, target)
+		target = next
+	}
+}
+
+// newUnresolvedReference creates new instance of unresolved reference
+func (parser *Parser) newUnresolvedReference(key, target, reason string) UnresolvedReference {
+	return UnresolvedReference{
+		Key:    key,
+		Path:   parser.describeKey(key),
+		Target: parser.describeKey(target),
+		Reason: reason,
+	}
+}
+
+// describeChain converts chain of keys into human-readable string of Consul paths
+func (parser *Parser) describeChain(chain []string) string {
+	// Cycle is rotated to start from the smallest key, so the same cycle is always described the same way
+	cycle := chain[:len(chain)-1]
+	start := 0
+	for index, key := range cycle {
+		if key < cycle[start] {
+			start = index
+		}
+	}
+
+	var paths []string
+	for index := 0; index <= len(cycle); index++ {
+		paths = append(paths, parser.describeKey(cycle[(start+index)%len(cycle)]))
+	}
+	return strings.Join(paths, " -> ")
+}
+
+// describeKey returns Consul path for given key (or key itself if path is not known)
+func (parser *Parser) describeKey(key string) string {
+	path, err := parser.referenceStorage.Get(key)
+	if err != nil {
+		return key
+	}
+	return path
+}
+
+// chainContains checks if chain of keys contains given key
+func (parser *Parser) chainContains(chain []string, key string) bool {
+	for _, value := range chain {
+		if value == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReferenceChainIsResolved(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("shared/db/host", `{"type":"string","value":"db.internal"}`),
+		pair("app/db/host", `{"type":"reference","value":"/shared/db/host"}`),
+		pair("worker/db/host", `{"type":"reference","value":"app/db/host"}`),
+	)
+	for _, key := range []string{"CONSUL_APP_DB_HOST", "CONSUL_WORKER_DB_HOST"} {
+		if value := configuration[key]; value != "db.internal" {
+			t.Fatalf("expected `%s` to be resolved, got %#v", key, value)
+		}
+	}
+	if unresolved := parser.UnresolvedReferences(); len(unresolved) != 0 {
+		t.Fatalf("expected no unresolved references, got %#v", unresolved)
+	}
+}
+
+func TestReferenceFollowsTargetChanges(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser,
+		pair("shared/host", `{"type":"string","value":"a"}`),
+		pair("app/host", `{"type":"reference","value":"shared/host"}`),
+	)
+	configuration := generate(parser,
+		pair("shared/host", `{"type":"string","value":"b"}`),
+		pair("app/host", `{"type":"reference","value":"shared/host"}`),
+	)
+	if value := configuration["CONSUL_APP_HOST"]; value != "b" {
+		t.Fatalf("expected reference to follow its target, got %#v", value)
+	}
+}
+
+func TestBrokenReferenceIsReported(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/host", `{"type":"reference","value":"shared/missing"}`))
+	if _, found := configuration["CONSUL_APP_HOST"]; found {
+		t.Fatalf("expected broken reference not to be published")
+	}
+	unresolved := parser.UnresolvedReferences()
+	if len(unresolved) != 1 || unresolved[0].Path != "app/host" {
+		t.Fatalf("expected broken reference to be reported, got %#v", unresolved)
+	}
+}
+
+func TestReferenceCycleIsReported(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/a", `{"type":"reference","value":"app/b"}`),
+		pair("app/b", `{"type":"reference","value":"app/c"}`),
+		pair("app/c", `{"type":"reference","value":"app/a"}`),
+		pair("app/d", `{"type":"reference","value":"app/a"}`),
+	)
+	if len(configuration) != 0 {
+		t.Fatalf("expected references in or into the cycle not to be published, got %#v", configuration)
+	}
+	unresolved := parser.UnresolvedReferences()
+	if len(unresolved) != 4 {
+		t.Fatalf("expected every reference to be reported, got %#v", unresolved)
+	}
+	for _, reference := range unresolved {
+		if !strings.Contains(strings.ToLower(reference.Reason), "cycle") && !strings.Contains(strings.ToLower(reference.Reason), "circular") {
+			t.Fatalf("expected cycle to be reported, got %#v", reference)
+		}
+	}
+}
+
+func TestEmptyReferenceIsRejected(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/host", `{"type":"reference","value":" "}`))
+	if rejected := parser.RejectedValues(); len(rejected) != 1 {
+		t.Fatalf("expected empty reference to be rejected, got %#v", rejected)
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+const (
+	// RolloutVariantNew is reported when agent is in the cohort which receives new value
+	RolloutVariantNew = "new"
+	// RolloutVariantOld is reported when agent keeps old value
+	RolloutVariantOld = "old"
+
+	// rolloutBuckets is a number of buckets agents are distributed between, allows percentage with two decimals
+	rolloutBuckets = 10000
+)
+
+// ValueRollout describes value which is rolled out to given percentage of agents
+type ValueRollout struct {
+	New        interface{} `json:"new"`
+	Old        interface{} `json:"old"`
+	Percentage float64     `json:"percentage"`
+}
+
+// RolloutDecision describes which variant of the rolled out value was chosen by current agent
+type RolloutDecision struct {
+	Key        string  `json:"key"`
+	Path       string  `json:"path"`
+	Variant    string  `json:"variant"`
+	Percentage float64 `json:"percentage"`
+}
+
+// applyRollout replaces value with new or old value of the rollout, depending on whether agent is in the cohort.
+// Agent is placed into a bucket by hash of its hostname and Consul path of the key, so it stays in the cohort while
+// percentage grows, while cohorts of different keys are independent of each other.
+func (parser *Parser) applyRollout(path, key string, value *ConsulValue) error {
+	if value.Rollout == nil {
+		return nil
+	}
+	rollout := value.Rollout
+	if rollout.Percentage < 0 || rollout.Percentage > 100 {
+		return fmt.Errorf("rollout percentage must be between 0 and 100, got %v", rollout.Percentage)
+	}
+	if rollout.New == nil {
+		return fmt.Errorf("rollout must have `new` value")
+	}
+
+	decision := RolloutDecision{
+		Key:        key,
+		Path:       path,
+		Variant:    RolloutVariantOld,
+		Percentage: rollout.Percentage,
+	}
+	if parser.rolloutBucket(parser.agentIdentity().Hostname, path) < rollout.Percentage*rolloutBuckets/100 {
+		decision.Variant = RolloutVariantNew
+		value.Value = rollout.New
+	} else if rollout.Old != nil {
+		value.Value = rollout.Old
+	}
+
+	parser.Lock()
+	defer parser.Unlock()
+	parser.rollouts[key] = decision
+	return nil
+}
+
+// rolloutBucket returns stable bucket of the agent for given key, computed from its hostname and Consul path of the key
+func (parser *Parser) rolloutBucket(hostname, path string) float64 {
+	hash := fnv.New32a()
+	hash.Write([]byte(hostname + "\x00" + path))
+	return float64(hash.Sum32() % rolloutBuckets)
+}
+
+// removeRolloutDecision removes rollout decision of the value which was rejected or replaced by a variant
+func (parser *Parser) removeRolloutDecision(key string) {
+	parser.Lock()
+	defer parser.Unlock()
+	delete(parser.rollouts, key)
+}
+
+// resetRollouts removes all rollout decisions
+func (parser *Parser) resetRollouts() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.rollouts = make(map[string]RolloutDecision)
+}
+
+// Rollouts returns list of rollout decisions made for the last received snapshot, sorted by Consul path
+func (parser *Parser) Rollouts() []RolloutDecision {
+	parser.RLock()
+	defer parser.RUnlock()
+	rollouts := make([]RolloutDecision, 0, len(parser.rollouts))
+	for _, decision := range parser.rollouts {
+		rollouts = append(rollouts, decision)
+	}
+	sort.Slice(rollouts, func(i, j int) bool {
+		return rollouts[i].Path < rollouts[j].Path
+	})
+	return rollouts
+}
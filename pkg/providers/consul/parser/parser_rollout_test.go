@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRolloutBucketIsStable(t *testing.T) {
+	parser := newTestParser(t)
+	first := parser.rolloutBucket("web-01", "app/pool")
+	if second := parser.rolloutBucket("web-01", "app/pool"); first != second {
+		t.Fatalf("expected the same bucket for the same agent and key, got %v and %v", first, second)
+	}
+	if first < 0 || first >= rolloutBuckets {
+		t.Fatalf("bucket %v is out of range", first)
+	}
+
+	differs := false
+	for index := 0; index < 10 && !differs; index++ {
+		differs = parser.rolloutBucket("web-01", fmt.Sprintf("app/key-%d", index)) != first
+	}
+	if !differs {
+		t.Fatalf("expected cohorts of different keys to be independent")
+	}
+}
+
+func TestRolloutChoosesVariantByPercentage(t *testing.T) {
+	tests := map[string]struct {
+		percentage float64
+		expected   float64
+		variant    string
+	}{
+		"nobody":   {percentage: 0, expected: 100, variant: RolloutVariantOld},
+		"everyone": {percentage: 100, expected: 200, variant: RolloutVariantNew},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser := newTestParser(t)
+			configuration := generate(parser, pair("app/pool", fmt.Sprintf(`{"type":"number","value":100,"rollout":{"new":200,"old":100,"percentage":%v}}`, test.percentage)))
+			if value := configuration["CONSUL_APP_POOL"]; value != test.expected {
+				t.Fatalf("expected %v, got %#v", test.expected, value)
+			}
+			rollouts := parser.Rollouts()
+			if len(rollouts) != 1 || rollouts[0].Variant != test.variant || rollouts[0].Path != "app/pool" {
+				t.Fatalf("expected `%s` variant to be reported, got %#v", test.variant, rollouts)
+			}
+		})
+	}
+}
+
+func TestRolloutCohortIsKeptWhilePercentageGrows(t *testing.T) {
+	parser := newTestParser(t)
+	bucket := parser.rolloutBucket(parser.agentIdentity().Hostname, "app/pool")
+	percentage := (bucket + 1) * 100 / rolloutBuckets
+	for _, current := range []float64{percentage, percentage + 10, 100} {
+		if current > 100 {
+			continue
+		}
+		configuration := generate(parser, pair("app/pool", fmt.Sprintf(`{"type":"number","value":100,"rollout":{"new":200,"percentage":%v}}`, current)))
+		if value := configuration["CONSUL_APP_POOL"]; value != float64(200) {
+			t.Fatalf("expected agent to stay in the cohort at %v%%, got %#v", current, value)
+		}
+	}
+}
+
+func TestVariantReplacesRolloutDecision(t *testing.T) {
+	parser := newTestParser(t)
+	hostname := parser.agentIdentity().Hostname
+	configuration := generate(parser, pair("app/pool", `{"type":"number","value":100,"rollout":{"new":200,"percentage":100},"variants":[{"selector":{"hostname":"`+hostname+`"},"value":500}]}`))
+	if value := configuration["CONSUL_APP_POOL"]; value != float64(500) {
+		t.Fatalf("expected variant value 500, got %#v", value)
+	}
+	if rollouts := parser.Rollouts(); len(rollouts) != 0 {
+		t.Fatalf("expected rollout not to be reported when variant is applied, got %#v", rollouts)
+	}
+}
+
+func TestRolloutWithInvalidPercentageIsRejected(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/pool", `{"type":"number","value":100,"rollout":{"new":200,"percentage":150}}`))
+	if rejected := parser.RejectedValues(); len(rejected) != 1 {
+		t.Fatalf("expected value with invalid percentage to be rejected, got %#v", rejected)
+	}
+	if rollouts := parser.Rollouts(); len(rollouts) != 0 {
+		t.Fatalf("expected no rollout decision for rejected value, got %#v", rollouts)
+	}
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"path"
+	"strings"
+)
+
+// ValueVariant describes value which is used on agents matching the selector
+type ValueVariant struct {
+	Selector *VariantSelector `json:"selector"`
+	Value    interface{}      `json:"value"`
+}
+
+// VariantSelector describes agent identity which should be matched for variant to be used,
+// every specified field must match, hostname and address support shell patterns (e.g. `web-*`)
+type VariantSelector struct {
+	Hostname    string            `json:"hostname"`
+	Address     string            `json:"address"`
+	Environment string            `json:"environment"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// AgentIdentity describes identity of the agent which is used to select value variants
+type AgentIdentity struct {
+	Hostname    string
+	Address     string
+	Environment string
+	Labels      map[string]string
+}
+
+// applyVariants replaces value with the first variant which selector matches current agent, reporting whether any matched
+func (parser *Parser) applyVariants(value *ConsulValue) bool {
+	if len(value.Variants) == 0 {
+		return false
+	}
+	identity := parser.agentIdentity()
+	for _, variant := range value.Variants {
+		if variant == nil || variant.Selector == nil {
+			continue
+		}
+		if variant.Selector.matches(identity) {
+			value.Value = variant.Value
+			return true
+		}
+	}
+	return false
+}
+
+// agentIdentity returns identity of current agent, it is collected once for every received snapshot
+func (parser *Parser) agentIdentity() *AgentIdentity {
+	parser.Lock()
+	defer parser.Unlock()
+	if parser.identity == nil {
+		parser.identity = &AgentIdentity{
+			Hostname:    parser.config.Agent.Network.Hostname(),
+			Address:     parser.config.Agent.Address(),
+			Environment: strings.TrimSpace(parser.config.Environment),
+			Labels:      parser.config.Agent.Labels,
+		}
+	}
+	return parser.identity
+}
+
+// resetAgentIdentity removes cached agent identity, so it will be collected again
+func (parser *Parser) resetAgentIdentity() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.identity = nil
+}
+
+// matches checks whether selector matches given agent identity
+func (selector *VariantSelector) matches(identity *AgentIdentity) bool {
+	if selector.Hostname != "" && !selector.matchPattern(selector.Hostname, identity.Hostname) {
+		return false
+	}
+	if selector.Address != "" && !selector.matchPattern(selector.Address, identity.Address) {
+		return false
+	}
+	if selector.Environment != "" && !strings.EqualFold(selector.Environment, identity.Environment) {
+		return false
+	}
+	for name, expected := range selector.Labels {
+		actual, ok := identity.Labels[name]
+		if !ok || !selector.matchPattern(expected, actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPattern checks whether value matches shell pattern
+func (selector *VariantSelector) matchPattern(pattern, value string) bool {
+	matched, err := path.Match(pattern, value)
+	if err != nil {
+		return pattern == value
+	}
+	return matched
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"os"
+	"testing"
+)
+
+func TestVariantMatchingHostnameIsUsed(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("hostname is not available - %s", err.Error())
+	}
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/host", `{"type":"string","value":"default","variants":[{"selector":{"hostname":"`+hostname+`"},"value":"own"}]}`))
+	if value := configuration["CONSUL_APP_HOST"]; value != "own" {
+		t.Fatalf("expected variant to be used, got %#v", value)
+	}
+}
+
+func TestVariantMatchingAddressPatternIsUsed(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/host", `{"type":"string","value":"default","variants":[{"selector":{"address":"127.0.0.*"},"value":"local"}]}`))
+	if value := configuration["CONSUL_APP_HOST"]; value != "local" {
+		t.Fatalf("expected variant to be used, got %#v", value)
+	}
+}
+
+func TestVariantRequiresAllLabelsToMatch(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Agent.Labels = map[string]string{"role": "web", "zone": "eu-1"}
+	configuration := generate(parser,
+		pair("app/host", `{"type":"string","value":"default","variants":[{"selector":{"labels":{"role":"web","zone":"us-*"}},"value":"us"}]}`),
+		pair("app/port", `{"type":"string","value":"default","variants":[{"selector":{"labels":{"role":"web","zone":"eu-*"}},"value":"eu"}]}`),
+	)
+	if value := configuration["CONSUL_APP_HOST"]; value != "default" {
+		t.Fatalf("expected default value when label does not match, got %#v", value)
+	}
+	if value := configuration["CONSUL_APP_PORT"]; value != "eu" {
+		t.Fatalf("expected variant to be used when labels match, got %#v", value)
+	}
+}
+
+func TestFirstMatchingVariantWins(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Environment = "production"
+	configuration := generate(parser, pair("app/host", `{"type":"string","value":"default","variants":[`+
+		`{"selector":{"environment":"staging"},"value":"staging"},`+
+		`{"selector":{"environment":"PRODUCTION"},"value":"first"},`+
+		`{"selector":{"address":"127.0.0.1"},"value":"second"}]}`))
+	if value := configuration["CONSUL_APP_HOST"]; value != "first" {
+		t.Fatalf("expected first matching variant to be used, got %#v", value)
+	}
+}
+
+func TestVariantTakesPrecedenceOverEnvironmentOverride(t *testing.T) {
+	parser := newTestParser(t)
+	parser.config.Environment = "production"
+	configuration := generate(parser, pair("app/host", `{"type":"string","value":"default","environments":{"production":"environment"},"variants":[{"selector":{"address":"127.0.0.1"},"value":"variant"}]}`))
+	if value := configuration["CONSUL_APP_HOST"]; value != "variant" {
+		t.Fatalf("expected variant to be used, got %#v", value)
+	}
+}
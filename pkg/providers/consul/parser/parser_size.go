@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits lists units size can be written in, with their size in bytes
+var sizeUnits = map[string]float64{
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// sizeUnitAliases maps human-readable unit names to size units, single letters are binary units (as in `-Xmx512m`)
+var sizeUnitAliases = map[string]string{
+	"bytes": "b",
+	"byte":  "b",
+	"k":     "kib",
+	"m":     "mib",
+	"g":     "gib",
+	"t":     "tib",
+}
+
+// sizePattern matches size with optional unit (e.g. `512MiB`, `1.5 GB`, `1024`)
+var sizePattern = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([a-zA-Z]*)$`)
+
+// SizeValue describes size which is written in requested unit
+type SizeValue struct {
+	Bytes int64
+	Unit  string
+}
+
+// TargetUnit returns unit requested by the value itself
+func (value *SizeValue) TargetUnit() string {
+	return value.Unit
+}
+
+// Convert converts size to given unit, bytes are used by default
+func (value *SizeValue) Convert(unit string) (interface{}, error) {
+	if unit == "" {
+		return value.Bytes, nil
+	}
+	_, factor, ok := findUnit(sizeUnits, sizeUnitAliases, unit)
+	if !ok {
+		return nil, fmt.Errorf("unknown size unit `%s`, supported units are %s", unit, unitNames(sizeUnits))
+	}
+	return convertToUnit(float64(value.Bytes), factor), nil
+}
+
+// sizeHandler handles SIZE values, accepting human input (e.g. `512MiB`, `1.5GB`, numbers are treated as bytes)
+type sizeHandler struct{}
+
+// Decode converts raw value into number of bytes
+func (handler *sizeHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	size, err := decodeSize(raw)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = (&SizeValue{}).Convert(context.Value.Unit); err != nil {
+		return nil, err
+	}
+	return size, nil
+}
+
+// Validate checks size (in bytes) against `min` and `max` constraints
+func (handler *sizeHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return validateNumber(context.Value, float64(decoded.(int64)))
+}
+
+// Render wraps size into value which is converted to the target unit when written
+func (handler *sizeHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	return &SizeValue{
+		Bytes: decoded.(int64),
+		Unit:  context.Value.Unit,
+	}, nil
+}
+
+// SupportsDelay reports that size values can be published with delay
+func (handler *sizeHandler) SupportsDelay() bool {
+	return true
+}
+
+// decodeSize converts received value to number of bytes
+func decodeSize(value interface{}) (int64, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		if typedValue >= 0 {
+			return int64(math.Round(typedValue)), nil
+		}
+	case string:
+		matches := sizePattern.FindStringSubmatch(strings.TrimSpace(typedValue))
+		if matches == nil {
+			break
+		}
+		number, _ := strconv.ParseFloat(matches[1], 64)
+		factor := float64(1)
+		if matches[2] != "" {
+			var ok bool
+			if _, factor, ok = findUnit(sizeUnits, sizeUnitAliases, matches[2]); !ok {
+				return 0, fmt.Errorf("`%s` has unknown size unit `%s`, supported units are %s", typedValue, matches[2], unitNames(sizeUnits))
+			}
+		}
+		return int64(math.Round(number * factor)), nil
+	}
+	return 0, fmt.Errorf("`%v` (%T) is not a valid size value", value, value)
+}
@@ -1,17 +1,54 @@
 package parser
 
-import "github.com/leads-su/logger"
+import (
+	"fmt"
+	"regexp"
+)
 
-// processString processes STRING and appends it to live data map
-func (parser *Parser) processString(key, value string, delayed interface{}) {
-	if parser.shouldDelay(delayed) {
-		delayedPublisher, err := parser.newDelayedPublisher(value, delayed.(string))
+// stringHandler handles STRING values, strings with `${...}` placeholders are rendered on configuration generation
+type stringHandler struct{}
+
+// Decode converts raw value into string
+func (handler *stringHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	text, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("`%v` (%T) is not a valid string value", raw, raw)
+	}
+	return text, nil
+}
+
+// Validate checks string against `min_length` and `regex` constraints
+func (handler *stringHandler) Validate(context *ValueContext, decoded interface{}) error {
+	return validateString(context.Value, decoded.(string))
+}
+
+// Render returns string as is, strings with placeholders are returned as interpolated string (unless value is literal)
+func (handler *stringHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	text := decoded.(string)
+	if !context.Value.Literal && isInterpolated(text) {
+		return &InterpolatedString{Template: text, Constraints: context.Value}, nil
+	}
+	return text, nil
+}
+
+// SupportsDelay reports that string values can be published with delay
+func (handler *stringHandler) SupportsDelay() bool {
+	return true
+}
+
+// validateString checks string against `min_length` and `regex` constraints
+func validateString(value *ConsulValue, text string) error {
+	if value.MinLength != nil && len(text) < *value.MinLength {
+		return fmt.Errorf("value must be at least %d character(s) long", *value.MinLength)
+	}
+	if value.Regex != "" {
+		expression, err := regexp.Compile(value.Regex)
 		if err != nil {
-			logger.Errorf("consul:parser:string", "failed to set delayed publisher for `%s` - %s", key, err.Error())
-		} else {
-			parser.setDelayedDataValue(key, delayedPublisher)
+			return fmt.Errorf("invalid regular expression `%s` - %s", value.Regex, err.Error())
+		}
+		if !expression.MatchString(text) {
+			return fmt.Errorf("`%s` does not match `%s`", text, value.Regex)
 		}
-	} else {
-		parser.setDataValue(key, value)
 	}
+	return nil
 }
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// UnitValue describes value which can be written in different units (e.g. durations and sizes)
+type UnitValue interface {
+	// TargetUnit returns unit requested by the value itself (empty if unit is not set)
+	TargetUnit() string
+	// Convert converts value to given unit, default unit of the value type is used if unit is empty
+	Convert(unit string) (interface{}, error)
+}
+
+// findUnit looks up unit by its name or alias (case-insensitive), returning its canonical name and factor
+func findUnit(units map[string]float64, aliases map[string]string, unit string) (string, float64, bool) {
+	name := strings.ToLower(strings.TrimSpace(unit))
+	if canonical, ok := aliases[name]; ok {
+		name = canonical
+	}
+	for unitName, factor := range units {
+		if strings.EqualFold(unitName, name) {
+			return unitName, factor, true
+		}
+	}
+	return "", 0, false
+}
+
+// convertToUnit divides value by unit factor, returning whole numbers as integers
+func convertToUnit(value, factor float64) interface{} {
+	converted := value / factor
+	if converted == math.Trunc(converted) && math.Abs(converted) < math.MaxInt64 {
+		return int64(converted)
+	}
+	return converted
+}
+
+// unitNames returns sorted list of unit names which can be used in error messages
+func unitNames(units map[string]float64, extra ...string) string {
+	names := append([]string{}, extra...)
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("`%s`", strings.Join(names, "`, `"))
+}
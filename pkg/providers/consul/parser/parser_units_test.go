@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationValuesAreDecoded(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/timeout", `{"type":"duration","value":"1h30m"}`),
+		pair("app/retention", `{"type":"duration","value":"2d12h"}`),
+		pair("app/interval", `{"type":"duration","value":90}`),
+		pair("app/ttl", `{"type":"duration","value":"1 d"}`),
+		pair("app/invalid", `{"type":"duration","value":"soon"}`),
+	)
+	expected := map[string]time.Duration{
+		"CONSUL_APP_TIMEOUT":   90 * time.Minute,
+		"CONSUL_APP_RETENTION": 60 * time.Hour,
+		"CONSUL_APP_INTERVAL":  90 * time.Second,
+		"CONSUL_APP_TTL":       24 * time.Hour,
+	}
+	for key, duration := range expected {
+		value, ok := configuration[key].(*DurationValue)
+		if !ok || value.Duration != duration {
+			t.Fatalf("expected `%s` to be %s, got %#v", key, duration, configuration[key])
+		}
+	}
+	if _, found := configuration["CONSUL_APP_INVALID"]; found {
+		t.Fatalf("expected invalid duration not to be published")
+	}
+}
+
+func TestSizeValuesAreDecoded(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/heap", `{"type":"size","value":"512m"}`),
+		pair("app/upload", `{"type":"size","value":"1.5 GB"}`),
+		pair("app/buffer", `{"type":"size","value":4096}`),
+		pair("app/unknown", `{"type":"size","value":"10 parsecs"}`),
+		pair("app/negative", `{"type":"size","value":-1}`),
+	)
+	expected := map[string]int64{
+		"CONSUL_APP_HEAP":   512 << 20,
+		"CONSUL_APP_UPLOAD": 1500000000,
+		"CONSUL_APP_BUFFER": 4096,
+	}
+	for key, bytes := range expected {
+		value, ok := configuration[key].(*SizeValue)
+		if !ok || value.Bytes != bytes {
+			t.Fatalf("expected `%s` to be %d bytes, got %#v", key, bytes, configuration[key])
+		}
+	}
+	for _, key := range []string{"CONSUL_APP_UNKNOWN", "CONSUL_APP_NEGATIVE"} {
+		if _, found := configuration[key]; found {
+			t.Fatalf("expected invalid size `%s` not to be published", key)
+		}
+	}
+}
+
+func TestUnitValuesAreConverted(t *testing.T) {
+	duration := &DurationValue{Duration: 90 * time.Second}
+	cases := []struct {
+		value    UnitValue
+		unit     string
+		expected interface{}
+	}{
+		{duration, "", "1m30s"},
+		{duration, "duration", "1m30s"},
+		{duration, "ms", int64(90000)},
+		{duration, "Minutes", 1.5},
+		{&SizeValue{Bytes: 1 << 20}, "", int64(1 << 20)},
+		{&SizeValue{Bytes: 1 << 20}, "KiB", int64(1024)},
+		{&SizeValue{Bytes: 1 << 20}, "k", int64(1024)},
+		{&SizeValue{Bytes: 1500}, "kb", 1.5},
+	}
+	for _, testCase := range cases {
+		converted, err := testCase.value.Convert(testCase.unit)
+		if err != nil || converted != testCase.expected {
+			t.Fatalf("expected %#v converted to `%s` to be %#v, got %#v (%v)", testCase.value, testCase.unit, testCase.expected, converted, err)
+		}
+	}
+	if _, err := duration.Convert("fortnights"); err == nil {
+		t.Fatalf("expected conversion to unknown unit to fail")
+	}
+}
+
+func TestUnitValuesAreValidated(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/timeout", `{"type":"duration","value":"2m","max":60}`),
+		pair("app/heap", `{"type":"size","value":"1KiB","min":1024}`),
+		pair("app/ttl", `{"type":"duration","value":"1m","unit":"fortnights"}`),
+	)
+	if _, found := configuration["CONSUL_APP_TIMEOUT"]; found {
+		t.Fatalf("expected duration above maximum not to be published")
+	}
+	if _, found := configuration["CONSUL_APP_HEAP"]; !found {
+		t.Fatalf("expected size matching minimum to be published")
+	}
+	if _, found := configuration["CONSUL_APP_TTL"]; found {
+		t.Fatalf("expected duration with unknown unit not to be published")
+	}
+}
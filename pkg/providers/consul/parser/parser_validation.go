@@ -0,0 +1,242 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/leads-su/logger"
+)
+
+// RejectedValue describes value which was not published because it violates its constraints
+type RejectedValue struct {
+	Key        string      `json:"key"`
+	Path       string      `json:"path"`
+	Value      interface{} `json:"value"`
+	Reason     string      `json:"reason"`
+	RejectedAt time.Time   `json:"rejected_at"`
+}
+
+// decodeValue decodes raw value with type handler and checks it against constraints declared in Consul value
+func (parser *Parser) decodeValue(handler ValueHandler, context *ValueContext, raw interface{}) (interface{}, error) {
+	if context.Value.Required && isEmptyValue(raw) {
+		return nil, fmt.Errorf("value is required")
+	}
+
+	decoded, err := handler.Decode(context, raw)
+	if err != nil {
+		return nil, err
+	}
+	// Strings with placeholders are checked once placeholders are resolved, see `renderTemplate`
+	if text, ok := decoded.(string); ok && context.Value.Type == "string" && !context.Value.Literal && isInterpolated(text) {
+		return decoded, nil
+	}
+	if err = handler.Validate(context, decoded); err != nil {
+		return nil, err
+	}
+	if err = validateEnum(handler, context, decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// validateEnum checks decoded value against `enum` constraint, allowed values are decoded by the same handler,
+// so `60s` and `1m` are the same duration and `1KiB` and `1024` are the same size.
+// Items of arrays are checked separately, other composite values cannot be constrained with `enum`
+func validateEnum(handler ValueHandler, context *ValueContext, decoded interface{}) error {
+	if len(context.Value.Enum) == 0 {
+		return nil
+	}
+	switch context.Value.Type {
+	case "array":
+		return nil
+	case "object", "file", "reference":
+		return fmt.Errorf("`enum` is not supported for `%s` values", context.Value.Type)
+	}
+	for _, allowed := range context.Value.Enum {
+		decodedAllowed, err := handler.Decode(context, allowed)
+		if err != nil {
+			return fmt.Errorf("allowed value `%v` is invalid - %s", allowed, err.Error())
+		}
+		if reflect.DeepEqual(decodedAllowed, decoded) {
+			return nil
+		}
+	}
+	return fmt.Errorf("`%v` is not one of allowed values %v", decoded, context.Value.Enum)
+}
+
+// constraintError is returned when rendered interpolated value violates constraints of its key
+type constraintError struct {
+	key   string
+	value string
+	err   error
+}
+
+// Error returns description of the violated constraint
+func (e *constraintError) Error() string {
+	return fmt.Sprintf("rendered value violates constraints - %s", e.err.Error())
+}
+
+// validateRenderedString checks rendered interpolated string against `min_length`, `regex` and `enum` constraints
+func validateRenderedString(value *ConsulValue, text string) error {
+	if err := validateString(value, text); err != nil {
+		return err
+	}
+	if len(value.Enum) != 0 && !enumContains(value.Enum, text) {
+		return fmt.Errorf("`%s` is not one of allowed values %v", text, value.Enum)
+	}
+	return nil
+}
+
+// validateArrayItem checks single array item against `items` type and constraints
+func validateArrayItem(value *ConsulValue, item interface{}) error {
+	switch value.Items {
+	case "":
+	case "number":
+		number, err := decodeNumber(item)
+		if err != nil {
+			return err
+		}
+		if err = validateNumber(value, number); err != nil {
+			return err
+		}
+	case "string":
+		text, ok := item.(string)
+		if !ok {
+			return fmt.Errorf("`%v` (%T) is not a valid string value", item, item)
+		}
+		if err := validateString(value, text); err != nil {
+			return err
+		}
+	case "boolean":
+		if _, err := decodeBoolean(item); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown array items type - `%s`", value.Items)
+	}
+
+	if len(value.Enum) != 0 && !enumContains(value.Enum, item) {
+		return fmt.Errorf("`%v` is not one of allowed values %v", item, value.Enum)
+	}
+	return nil
+}
+
+// enumContains checks whether list of allowed values contains given value
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyValue checks whether value is missing or empty
+func isEmptyValue(value interface{}) bool {
+	switch typedValue := value.(type) {
+	case nil:
+		return true
+	case string:
+		return typedValue == ""
+	case []interface{}:
+		return len(typedValue) == 0
+	case map[string]interface{}:
+		return len(typedValue) == 0
+	}
+	return false
+}
+
+// rejectValue stores information about value which violates its constraints, previously published value is kept
+func (parser *Parser) rejectValue(path, key string, value *ConsulValue, err error) {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.rejectedValues[key] = RejectedValue{
+		Key:        key,
+		Path:       path,
+		Value:      value.Value,
+		Reason:     err.Error(),
+		RejectedAt: time.Now().UTC(),
+	}
+}
+
+// reportRejectedValues logs and notifies about values which were rejected for the first time (or for a new reason)
+func (parser *Parser) reportRejectedValues(previous map[string]RejectedValue) {
+	var messages []string
+	parser.Lock()
+	for key, rejected := range parser.rejectedValues {
+		if previousRejection, ok := previous[key]; ok && previousRejection.Reason == rejected.Reason && reflect.DeepEqual(previousRejection.Value, rejected.Value) {
+			rejected.RejectedAt = previousRejection.RejectedAt
+			parser.rejectedValues[key] = rejected
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("value for `%s` was rejected, keeping last good value - %s", rejected.Path, rejected.Reason))
+	}
+	parser.Unlock()
+
+	for _, message := range messages {
+		logger.Errorf("consul:parser:validation", message)
+		parser.sendErrorNotification(message)
+	}
+}
+
+// newRenderRejection creates rejection of rendered interpolated value
+func (parser *Parser) newRenderRejection(key string, err *constraintError) RejectedValue {
+	path, pathErr := parser.referenceStorage.Get(key)
+	if pathErr != nil {
+		path = key
+	}
+	return RejectedValue{
+		Key:        key,
+		Path:       path,
+		Value:      err.value,
+		Reason:     err.Error(),
+		RejectedAt: time.Now().UTC(),
+	}
+}
+
+// reportRenderRejections stores rejections of rendered interpolated values, logging and notifying about
+// values which were rejected for the first time (or for a new reason)
+func (parser *Parser) reportRenderRejections(rejected map[string]RejectedValue) {
+	var messages []string
+	parser.Lock()
+	for key, previous := range parser.renderRejections {
+		if _, ok := rejected[key]; ok {
+			continue
+		}
+		if current, ok := parser.rejectedValues[key]; ok && current.Reason == previous.Reason && current.RejectedAt == previous.RejectedAt {
+			delete(parser.rejectedValues, key)
+		}
+	}
+	for key, rejection := range rejected {
+		if previous, ok := parser.renderRejections[key]; ok && previous.Reason == rejection.Reason && reflect.DeepEqual(previous.Value, rejection.Value) {
+			rejection.RejectedAt = previous.RejectedAt
+		} else {
+			messages = append(messages, fmt.Sprintf("value for `%s` was rejected, keeping last good value - %s", rejection.Path, rejection.Reason))
+		}
+		rejected[key] = rejection
+		parser.rejectedValues[key] = rejection
+	}
+	parser.renderRejections = rejected
+	parser.Unlock()
+
+	for _, message := range messages {
+		logger.Errorf("consul:parser:validation", message)
+		parser.sendErrorNotification(message)
+	}
+}
+
+// RejectedValues returns list of values which were rejected during last processing of Consul data
+func (parser *Parser) RejectedValues() []RejectedValue {
+	parser.RLock()
+	defer parser.RUnlock()
+	rejected := make([]RejectedValue, 0, len(parser.rejectedValues))
+	for _, value := range parser.rejectedValues {
+		rejected = append(rejected, value)
+	}
+	sort.Slice(rejected, func(i, j int) bool {
+		return rejected[i].Path < rejected[j].Path
+	})
+	return rejected
+}
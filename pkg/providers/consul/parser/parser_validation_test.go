@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValueViolatingConstraintsIsRejected(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/port", `{"type":"number","value":8080,"min":1,"max":65535}`))
+
+	configuration := generate(parser, pair("app/port", `{"type":"number","value":70000,"min":1,"max":65535}`))
+	if value := configuration["CONSUL_APP_PORT"]; value != float64(8080) {
+		t.Fatalf("expected last good value 8080 to be kept, got %#v", value)
+	}
+	rejected := parser.RejectedValues()
+	if len(rejected) != 1 || rejected[0].Path != "app/port" {
+		t.Fatalf("expected `app/port` to be rejected, got %#v", rejected)
+	}
+}
+
+func TestRegexIsCheckedAgainstRenderedValue(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/port", `{"type":"number","value":8080}`),
+		pair("app/listen", `{"type":"string","value":"${app/port}","regex":"^[0-9]+$"}`),
+	)
+	if value := configuration["CONSUL_APP_LISTEN"]; value != "8080" {
+		t.Fatalf("expected rendered value `8080`, got %#v", value)
+	}
+	if rejected := parser.RejectedValues(); len(rejected) != 0 {
+		t.Fatalf("expected no rejected values, got %#v", rejected)
+	}
+
+	configuration = generate(parser,
+		pair("app/port", `{"type":"string","value":"http"}`),
+		pair("app/listen", `{"type":"string","value":"${app/port}","regex":"^[0-9]+$"}`),
+	)
+	if value := configuration["CONSUL_APP_LISTEN"]; value != "8080" {
+		t.Fatalf("expected last good value `8080` to be kept, got %#v", value)
+	}
+	rejected := parser.RejectedValues()
+	if len(rejected) != 1 || rejected[0].Path != "app/listen" || rejected[0].Value != "http" {
+		t.Fatalf("expected rendered value of `app/listen` to be rejected, got %#v", rejected)
+	}
+
+	configuration = generate(parser,
+		pair("app/port", `{"type":"number","value":9090}`),
+		pair("app/listen", `{"type":"string","value":"${app/port}","regex":"^[0-9]+$"}`),
+	)
+	if value := configuration["CONSUL_APP_LISTEN"]; value != "9090" {
+		t.Fatalf("expected rendered value `9090`, got %#v", value)
+	}
+	if rejected := parser.RejectedValues(); len(rejected) != 0 {
+		t.Fatalf("expected rejection to be cleared, got %#v", rejected)
+	}
+}
+
+func TestEnumIsCheckedAgainstRenderedValue(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/level", `{"type":"string","value":"verbose"}`),
+		pair("app/log", `{"type":"string","value":"${app/level}","enum":["debug","info"]}`),
+	)
+	if value, found := configuration["CONSUL_APP_LOG"]; found {
+		t.Fatalf("expected rendered value outside of enum to be rejected, got %#v", value)
+	}
+}
+
+func TestEnumOfDurationComparesDurations(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/timeout", `{"type":"duration","value":"60s","enum":["30s","1m"]}`))
+	if _, found := configuration["CONSUL_APP_TIMEOUT"]; !found {
+		t.Fatalf("expected duration equal to allowed value to be published, rejected: %#v", parser.RejectedValues())
+	}
+
+	configuration = generate(parser, pair("app/timeout", `{"type":"duration","value":"45s","enum":["30s","1m"]}`))
+	rejected := parser.RejectedValues()
+	if len(rejected) != 1 || !strings.Contains(rejected[0].Reason, "allowed values") {
+		t.Fatalf("expected duration outside of enum to be rejected, got %#v", rejected)
+	}
+}
+
+func TestEnumOfSizeComparesSizes(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/buffer", `{"type":"size","value":"1KiB","enum":["1024","2KiB"]}`))
+	if _, found := configuration["CONSUL_APP_BUFFER"]; !found {
+		t.Fatalf("expected size equal to allowed value to be published, rejected: %#v", parser.RejectedValues())
+	}
+
+	generate(parser, pair("app/buffer", `{"type":"size","value":"4KiB","enum":["1024","2KiB"]}`))
+	if rejected := parser.RejectedValues(); len(rejected) != 1 {
+		t.Fatalf("expected size outside of enum to be rejected, got %#v", rejected)
+	}
+}
+
+func TestEnumIsRejectedForObjects(t *testing.T) {
+	parser := newTestParser(t)
+	generate(parser, pair("app/db", `{"type":"object","value":{"host":"h"},"enum":["h"]}`))
+	rejected := parser.RejectedValues()
+	if len(rejected) != 1 || !strings.Contains(rejected[0].Reason, "not supported") {
+		t.Fatalf("expected object with enum to be rejected, got %#v", rejected)
+	}
+}
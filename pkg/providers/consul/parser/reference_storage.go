@@ -7,15 +7,17 @@ import (
 
 type ReferenceStorage struct {
 	sync.RWMutex
-	pathToKey map[string]string
-	keyToPath map[string]string
+	pathToKey   map[string]string
+	keyToPath   map[string]string
+	keyToSource map[string]string
 }
 
 // NewReferenceStorage creates new instance of reference storage
 func NewReferenceStorage() *ReferenceStorage {
 	return &ReferenceStorage{
-		pathToKey: make(map[string]string),
-		keyToPath: make(map[string]string),
+		pathToKey:   make(map[string]string),
+		keyToPath:   make(map[string]string),
+		keyToSource: make(map[string]string),
 	}
 }
 
@@ -25,6 +27,36 @@ func (storage *ReferenceStorage) Set(path, key string) {
 	storage.setKeyToPathReference(key, path)
 }
 
+// SetDerived add value derived from another Consul key (e.g. object field) to references storage
+func (storage *ReferenceStorage) SetDerived(sourcePath, path, key string) {
+	storage.Set(path, key)
+	storage.setKeyToSourceReference(key, sourcePath)
+}
+
+// GetSource retrieve Consul key path which produced given key
+func (storage *ReferenceStorage) GetSource(key string) (string, error) {
+	storage.RLock()
+	source, ok := storage.keyToSource[key]
+	storage.RUnlock()
+	if ok {
+		return source, nil
+	}
+	return storage.Get(key)
+}
+
+// DerivedKeys retrieve list of keys derived from given Consul key path
+func (storage *ReferenceStorage) DerivedKeys(sourcePath string) []string {
+	storage.RLock()
+	defer storage.RUnlock()
+	var keys []string
+	for key, source := range storage.keyToSource {
+		if source == sourcePath {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 // Get retrieve value from references storage
 func (storage *ReferenceStorage) Get(pathOrKey string) (string, error) {
 	if storage.pathToKeyHas(pathOrKey) {
@@ -40,6 +72,7 @@ func (storage *ReferenceStorage) Get(pathOrKey string) (string, error) {
 func (storage *ReferenceStorage) remove(path, key string) {
 	storage.removePathToKeyReference(path)
 	storage.removeKeyToPathReference(key)
+	storage.removeKeyToSourceReference(key)
 }
 
 // setPathToKeyReference set path to key reference
@@ -115,3 +148,17 @@ func (storage *ReferenceStorage) keyToPathGet(key string) (string, error) {
 func (storage *ReferenceStorage) keyToPathList() map[string]string {
 	return storage.keyToPath
 }
+
+// setKeyToSourceReference set key to source path reference
+func (storage *ReferenceStorage) setKeyToSourceReference(key, sourcePath string) {
+	storage.Lock()
+	defer storage.Unlock()
+	storage.keyToSource[key] = sourcePath
+}
+
+// removeKeyToSourceReference remove key to source path reference
+func (storage *ReferenceStorage) removeKeyToSourceReference(key string) {
+	storage.Lock()
+	defer storage.Unlock()
+	delete(storage.keyToSource, key)
+}
@@ -3,18 +3,25 @@ package parser
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
-// formatKey formats key and makes it a valid env variable
+// formatKey formats key into internal identifier which is unique for every Consul path,
+// name written to configuration file is produced from the path by VariableName
 func (parser *Parser) formatKey(key string) string {
 	return "CONSUL_" + strings.ReplaceAll(strings.ReplaceAll(strings.ToUpper(key), "/", "_"), "-", "_")
 }
 
 // setReferenceValue adds data to reference map
-func (parser *Parser) setReferenceValue(key, toKey string) {
+func (parser *Parser) setReferenceValue(key, toKey string, modifiers []*ReferenceModifier) {
 	parser.Lock()
 	defer parser.Unlock()
 	parser.referenceMap[key] = toKey
+	if len(modifiers) > 0 {
+		parser.referenceModifiers[key] = modifiers
+	} else {
+		delete(parser.referenceModifiers, key)
+	}
 }
 
 // removeReferenceValue removes data from reference map
@@ -28,6 +35,7 @@ func (parser *Parser) removeReferenceValue(key string) {
 		}
 	}
 	parser.referenceMap = referenceMap
+	delete(parser.referenceModifiers, key)
 }
 
 // getDataValue retrieves data from live data map
@@ -74,8 +82,139 @@ func (parser *Parser) removeDelayedDataValue(key string) {
 	delayedData := make(map[string]*DelayedPublishing)
 	for index, value := range parser.delayedData {
 		if index != key {
-			delayedData[key] = value
+			delayedData[index] = value
 		}
 	}
 	parser.delayedData = delayedData
 }
+
+// resetDelayedData removes all values from delayed data map
+func (parser *Parser) resetDelayedData() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.delayedData = make(map[string]*DelayedPublishing)
+}
+
+// setTemplateDataValue adds value to template data map, along with constraints its rendered value is checked against
+func (parser *Parser) setTemplateDataValue(key, value string, constraints *ConsulValue) {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.templateData[key] = value
+	if constraints != nil {
+		parser.templateConstraints[key] = constraints
+	} else {
+		delete(parser.templateConstraints, key)
+	}
+}
+
+// resetReferenceData removes all values from reference map
+func (parser *Parser) resetReferenceData() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.referenceMap = make(map[string]string)
+	parser.referenceModifiers = make(map[string][]*ReferenceModifier)
+}
+
+// setKeySource adds Consul key information to key sources map
+func (parser *Parser) setKeySource(path string, source *KeySource) {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.keySources[path] = source
+}
+
+// resetKeySources removes all values from key sources map
+func (parser *Parser) resetKeySources() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.keySources = make(map[string]*KeySource)
+}
+
+// resetTemplateData removes all values from template data map
+func (parser *Parser) resetTemplateData() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.templateData = make(map[string]string)
+	parser.templateConstraints = make(map[string]*ConsulValue)
+}
+
+// registerKey adds key to reference storage and marks it as present in current snapshot
+func (parser *Parser) registerKey(sourcePath, path, key string) {
+	if sourcePath == path {
+		parser.referenceStorage.Set(path, key)
+	} else {
+		parser.referenceStorage.SetDerived(sourcePath, path, key)
+	}
+	parser.Lock()
+	defer parser.Unlock()
+	parser.snapshotKeys[key] = path
+}
+
+// resetSnapshotKeys starts new snapshot and returns keys which were present in the previous one
+func (parser *Parser) resetSnapshotKeys() map[string]string {
+	parser.Lock()
+	defer parser.Unlock()
+	previousKeys := parser.snapshotKeys
+	parser.snapshotKeys = make(map[string]string)
+	return previousKeys
+}
+
+// removeTemplateDataValue removes value from template data map
+func (parser *Parser) removeTemplateDataValue(key string) {
+	parser.Lock()
+	defer parser.Unlock()
+	delete(parser.templateData, key)
+	delete(parser.templateConstraints, key)
+}
+
+// setExpiringDataValue adds value to expiring data map
+func (parser *Parser) setExpiringDataValue(key string, value *ExpiringValue) {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.expiringData[key] = value
+}
+
+// removeExpiringDataValue removes value from expiring data map
+func (parser *Parser) removeExpiringDataValue(key string) {
+	parser.Lock()
+	defer parser.Unlock()
+	delete(parser.expiringData, key)
+}
+
+// resetExpiringData removes all values from expiring data map
+func (parser *Parser) resetExpiringData() {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.expiringData = make(map[string]*ExpiringValue)
+}
+
+// setRevertedDataValue marks value as reverted after given expiration time
+func (parser *Parser) setRevertedDataValue(key string, expiresOn time.Time) {
+	parser.Lock()
+	defer parser.Unlock()
+	parser.revertedData[key] = expiresOn
+}
+
+// removeRevertedDataValue removes value from reverted data map
+func (parser *Parser) removeRevertedDataValue(key string) {
+	parser.Lock()
+	defer parser.Unlock()
+	delete(parser.revertedData, key)
+}
+
+// resetRejectedValues removes all values from rejected values map and returns previous ones
+func (parser *Parser) resetRejectedValues() map[string]RejectedValue {
+	parser.Lock()
+	defer parser.Unlock()
+	previous := parser.rejectedValues
+	parser.rejectedValues = make(map[string]RejectedValue)
+	return previous
+}
+
+// resetQuarantinedValues removes all values from quarantined values map and returns previous ones
+func (parser *Parser) resetQuarantinedValues() map[string]QuarantinedValue {
+	parser.Lock()
+	defer parser.Unlock()
+	previous := parser.quarantinedValues
+	parser.quarantinedValues = make(map[string]QuarantinedValue)
+	return previous
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ValueContext describes Consul key which value is being processed
+type ValueContext struct {
+	// Path is a Consul key path
+	Path string
+
+	// Key is an internal key produced from the path
+	Key string
+
+	// Value is a decoded Consul value
+	Value *ConsulValue
+}
+
+// ValueHandler describes handler responsible for a single value type
+type ValueHandler interface {
+	// Decode converts raw value received from Consul into typed value
+	Decode(context *ValueContext, raw interface{}) (interface{}, error)
+
+	// Validate checks decoded value against constraints declared in Consul value
+	Validate(context *ValueContext, decoded interface{}) error
+
+	// Render converts decoded value into value which is published to live data map
+	Render(context *ValueContext, decoded interface{}) (interface{}, error)
+}
+
+// DelayedValueHandler is implemented by handlers which support delayed publishing of their values
+type DelayedValueHandler interface {
+	SupportsDelay() bool
+}
+
+var (
+	valueHandlersLock sync.RWMutex
+	valueHandlers     = make(map[string]ValueHandler)
+)
+
+// RegisterValueHandler registers handler for given value type, replacing handler registered for it before.
+// Custom types can be added from a separate file (optionally guarded by a build tag) with `init` function.
+func RegisterValueHandler(valueType string, handler ValueHandler) {
+	valueHandlersLock.Lock()
+	defer valueHandlersLock.Unlock()
+	valueHandlers[valueType] = handler
+}
+
+// RegisteredValueTypes returns list of value types which have registered handler
+func RegisteredValueTypes() []string {
+	valueHandlersLock.RLock()
+	defer valueHandlersLock.RUnlock()
+	types := make([]string, 0, len(valueHandlers))
+	for valueType := range valueHandlers {
+		types = append(types, valueType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// getValueHandler returns handler registered for given value type
+func getValueHandler(valueType string) (ValueHandler, error) {
+	valueHandlersLock.RLock()
+	defer valueHandlersLock.RUnlock()
+	if handler, ok := valueHandlers[valueType]; ok {
+		return handler, nil
+	}
+	return nil, fmt.Errorf("unknown value type - `%s`", valueType)
+}
+
+// supportsDelay checks whether handler supports delayed publishing
+func supportsDelay(handler ValueHandler) bool {
+	if delayedHandler, ok := handler.(DelayedValueHandler); ok {
+		return delayedHandler.SupportsDelay()
+	}
+	return false
+}
+
+func init() {
+	RegisterValueHandler("array", &arrayHandler{})
+	RegisterValueHandler("boolean", &booleanHandler{})
+	RegisterValueHandler("duration", &durationHandler{})
+	RegisterValueHandler("file", &fileHandler{})
+	RegisterValueHandler("number", &numberHandler{})
+	RegisterValueHandler("object", &objectHandler{})
+	RegisterValueHandler("reference", &referenceHandler{})
+	RegisterValueHandler("size", &sizeHandler{})
+	RegisterValueHandler("string", &stringHandler{})
+}
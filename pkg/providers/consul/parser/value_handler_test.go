@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// upperCaseHandler is a value handler which publishes non-empty strings in upper case
+type upperCaseHandler struct{}
+
+func (handler *upperCaseHandler) Decode(context *ValueContext, raw interface{}) (interface{}, error) {
+	value, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected string, got %T", raw)
+	}
+	return value, nil
+}
+
+func (handler *upperCaseHandler) Validate(context *ValueContext, decoded interface{}) error {
+	if decoded == "" {
+		return fmt.Errorf("value cannot be empty")
+	}
+	return nil
+}
+
+func (handler *upperCaseHandler) Render(context *ValueContext, decoded interface{}) (interface{}, error) {
+	return strings.ToUpper(decoded.(string)), nil
+}
+
+func TestRegisteredValueHandlerIsUsed(t *testing.T) {
+	RegisterValueHandler("test-upper-case", &upperCaseHandler{})
+	parser := newTestParser(t)
+	configuration := generate(parser,
+		pair("app/mode", `{"type":"test-upper-case","value":"active"}`),
+		pair("app/name", `{"type":"test-upper-case","value":""}`),
+		pair("app/port", `{"type":"test-upper-case","value":80}`),
+	)
+	if value := configuration["CONSUL_APP_MODE"]; value != "ACTIVE" {
+		t.Fatalf("expected value rendered by registered handler, got %#v", value)
+	}
+	if _, found := configuration["CONSUL_APP_NAME"]; found {
+		t.Fatalf("expected value rejected by handler validation not to be published")
+	}
+	if _, found := configuration["CONSUL_APP_PORT"]; found {
+		t.Fatalf("expected value which handler cannot decode not to be published")
+	}
+}
+
+func TestRegisteredValueTypes(t *testing.T) {
+	RegisterValueHandler("test-upper-case", &upperCaseHandler{})
+	types := strings.Join(RegisteredValueTypes(), ",")
+	for _, valueType := range []string{"string", "number", "boolean", "object", "file", "reference", "test-upper-case"} {
+		if !strings.Contains(","+types+",", ","+valueType+",") {
+			t.Fatalf("expected `%s` to be registered, got `%s`", valueType, types)
+		}
+	}
+}
+
+func TestValueOfUnknownTypeIsNotPublished(t *testing.T) {
+	parser := newTestParser(t)
+	configuration := generate(parser, pair("app/mode", `{"type":"unknown","value":"active"}`))
+	if _, found := configuration["CONSUL_APP_MODE"]; found {
+		t.Fatalf("expected value of unknown type not to be published")
+	}
+}
+
+func TestHandlerWithoutDelaySupportPublishesImmediately(t *testing.T) {
+	RegisterValueHandler("test-upper-case", &upperCaseHandler{})
+	parser := newTestParser(t)
+	delayed := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	configuration := generate(parser, pair("app/mode", `{"type":"test-upper-case","value":"active","delayed":"`+delayed+`"}`))
+	if value := configuration["CONSUL_APP_MODE"]; value != "ACTIVE" {
+		t.Fatalf("expected value to be published immediately, got %#v", value)
+	}
+}
@@ -0,0 +1,88 @@
+package consul
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/history"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/storage"
+	"github.com/leads-su/logger"
+)
+
+// Scheduler wakes up at the publishing time of delayed values (and expiration time of expiring values)
+// and re-renders files affected by them
+type Scheduler struct {
+	parser  *parser.Parser
+	storage *storage.ConsulStorage
+	history *history.History
+	timer   *time.Timer
+}
+
+// newScheduler creates new instance of delayed publishing scheduler
+func newScheduler(consulParser *parser.Parser, consulStorage *storage.ConsulStorage, consulHistory *history.History) *Scheduler {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return &Scheduler{
+		parser:  consulParser,
+		storage: consulStorage,
+		history: consulHistory,
+		timer:   timer,
+	}
+}
+
+// Channel returns channel which receives value whenever the next delayed value should be published
+func (scheduler *Scheduler) Channel() <-chan time.Time {
+	return scheduler.timer.C
+}
+
+// Reschedule sets timer to the closest publishing time of delayed values or expiration time of expiring values
+func (scheduler *Scheduler) Reschedule() {
+	if !scheduler.timer.Stop() {
+		select {
+		case <-scheduler.timer.C:
+		default:
+		}
+	}
+
+	next, ok := scheduler.parser.NextPublishing()
+	if expiration, hasExpiration := scheduler.parser.NextExpiration(); hasExpiration && (!ok || expiration.Before(next)) {
+		next, ok = expiration, true
+	}
+	if !ok {
+		return
+	}
+	logger.Debugf("consul:scheduler", "next scheduled change will be applied at %s", next.Format(time.RFC3339))
+	scheduler.timer.Reset(time.Until(next))
+}
+
+// Publish publishes delayed values which reached their publishing time, reverts expired values
+// and rewrites only affected files
+func (scheduler *Scheduler) Publish() {
+	previous := scheduler.parser.LiveData()
+	configuration := scheduler.parser.GenerateConfiguration()
+
+	var changedKeys []string
+	for key, value := range configuration {
+		if previousValue, ok := previous[key]; !ok || !reflect.DeepEqual(previousValue, value) {
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	for key := range previous {
+		if _, ok := configuration[key]; !ok {
+			changedKeys = append(changedKeys, key)
+		}
+	}
+
+	if len(changedKeys) != 0 {
+		logger.Infof("consul:scheduler", "applied scheduled changes, %d key(s) changed", len(changedKeys))
+		scheduler.storage.ProcessChangedKeys(configuration, changedKeys)
+		scheduler.history.Record(scheduler.parser)
+	}
+	scheduler.Reschedule()
+}
+
+// Stop stops scheduler timer
+func (scheduler *Scheduler) Stop() {
+	scheduler.timer.Stop()
+}
@@ -0,0 +1,110 @@
+package consul
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/leads-su/broker"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/history"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+	"github.com/leads-su/consul-config-manager/pkg/providers/consul/storage"
+)
+
+// newTestScheduler creates scheduler which writes files to temporary directory
+func newTestScheduler(t *testing.T, emptyFiles string) (*Scheduler, string) {
+	t.Helper()
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Agent.Network.Address = "127.0.0.1"
+	config.Consul.WriteTo = t.TempDir()
+	config.Consul.EmptyFiles = emptyFiles
+	config.Consul.History.Enabled = false
+
+	consulParser := parser.NewParser(config)
+	consulStorage := storage.NewStorage(config, consulParser)
+	return newScheduler(consulParser, consulStorage, history.NewHistory(config)), config.Consul.WriteTo
+}
+
+// process processes snapshot of pairs the way it is done when changes are received from Consul
+func process(scheduler *Scheduler, pairs ...*api.KVPair) {
+	scheduler.parser.ProcessReceivedData(pairs)
+	scheduler.storage.ProcessChanges(scheduler.parser.GenerateConfiguration())
+}
+
+func TestExpiredFileValueIsRemoved(t *testing.T) {
+	scheduler, writeTo := newTestScheduler(t, consul.EmptyFilesRemove)
+	expires := time.Now().Add(time.Second).UTC().Format(time.RFC3339)
+	process(scheduler,
+		&api.KVPair{Key: "app/certificate", Value: []byte(`{"type":"file","path":"certs/tls.pem","value":"secret","expires":"` + expires + `"}`), ModifyIndex: 1},
+		&api.KVPair{Key: "app/host", Value: []byte(`{"type":"string","value":"localhost"}`), ModifyIndex: 1},
+	)
+
+	certificatePath := filepath.Join(writeTo, "certs", "tls.pem")
+	content, err := ioutil.ReadFile(certificatePath)
+	if err != nil {
+		t.Fatalf("file value was not written - %s", err.Error())
+	}
+	if string(content) != "secret" {
+		t.Fatalf("unexpected content of file value: %q", content)
+	}
+
+	time.Sleep(2 * time.Second)
+	scheduler.Publish()
+
+	if _, err = os.Stat(certificatePath); !os.IsNotExist(err) {
+		t.Fatalf("file of expired value was not removed")
+	}
+	if _, err = os.Stat(filepath.Join(writeTo, "app", "host.env")); err != nil {
+		t.Fatalf("configuration file of other values was removed - %s", err.Error())
+	}
+}
+
+func TestDelayedFileValueIsWritten(t *testing.T) {
+	scheduler, writeTo := newTestScheduler(t, consul.EmptyFilesTruncate)
+	delayed := time.Now().Add(time.Second).UTC().Format(time.RFC3339)
+	process(scheduler,
+		&api.KVPair{Key: "app/certificate", Value: []byte(`{"type":"file","path":"certs/tls.pem","value":"secret","delayed":"` + delayed + `"}`), ModifyIndex: 1},
+	)
+
+	certificatePath := filepath.Join(writeTo, "certs", "tls.pem")
+	if _, err := os.Stat(certificatePath); !os.IsNotExist(err) {
+		t.Fatalf("delayed file value was written before its publishing time")
+	}
+
+	time.Sleep(2 * time.Second)
+	scheduler.Publish()
+
+	content, err := ioutil.ReadFile(certificatePath)
+	if err != nil {
+		t.Fatalf("delayed file value was not written - %s", err.Error())
+	}
+	if string(content) != "secret" {
+		t.Fatalf("unexpected content of delayed file value: %q", content)
+	}
+}
+
+func TestSchedulerFiresAtPublishingTime(t *testing.T) {
+	scheduler, _ := newTestScheduler(t, consul.EmptyFilesTruncate)
+	defer scheduler.Stop()
+	delayed := time.Now().Add(time.Second).UTC().Format(time.RFC3339)
+	process(scheduler, &api.KVPair{Key: "app/mode", Value: []byte(`{"type":"string","value":"new","delayed":"` + delayed + `"}`), ModifyIndex: 1})
+
+	scheduler.Reschedule()
+	select {
+	case <-scheduler.Channel():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("scheduler did not fire at publishing time")
+	}
+	scheduler.Publish()
+	if value := scheduler.parser.LiveData()["CONSUL_APP_MODE"]; value != "new" {
+		t.Fatalf("expected delayed value to be published, got %#v", value)
+	}
+}
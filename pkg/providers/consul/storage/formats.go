@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+	"github.com/leads-su/logger"
+)
+
+// formatExtensions lists supported output formats with extension of the files they produce
+var formatExtensions = map[string]string{
+	consul.FormatEnv:        ".env",
+	consul.FormatExport:     ".sh",
+	consul.FormatJSON:       ".json",
+	consul.FormatYAML:       ".yml",
+	consul.FormatTOML:       ".toml",
+	consul.FormatINI:        ".ini",
+	consul.FormatProperties: ".properties",
+}
+
+// formatEncoders lists functions which encode variables of a single file in supported output formats
+var formatEncoders = map[string]func(path string, variables []NamedVariable) []byte{
+	consul.FormatEnv:        encodeEnv,
+	consul.FormatExport:     encodeExport,
+	consul.FormatJSON:       encodeJSON,
+	consul.FormatYAML:       encodeYAML,
+	consul.FormatTOML:       encodeTOML,
+	consul.FormatINI:        encodeINI,
+	consul.FormatProperties: encodeProperties,
+}
+
+// bareKeyPattern matches keys which can be written without quotes in YAML and TOML
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// yamlResolvedKeyPattern matches bare keys which YAML resolves to booleans, null or numbers (e.g. `on`, `no`, `null`, `1_000`),
+// keys starting with digit or dash are always quoted
+var yamlResolvedKeyPattern = regexp.MustCompile(`^(?i:y|n|yes|no|true|false|on|off|null)$|^[0-9-]`)
+
+// fileFormat returns output format of the file key under given Consul path is written to,
+// format of the rule with the longest matching path wins, `consul.format` is used otherwise
+func (cs *ConsulStorage) fileFormat(path string) string {
+	format, matchedPath := cs.config.Consul.Format, ""
+	for _, rule := range cs.config.Consul.Formats {
+		if rule == nil {
+			continue
+		}
+		rulePath := strings.Trim(rule.Path, "/")
+		if rulePath == "" || (path != rulePath && !strings.HasPrefix(path, rulePath+"/")) {
+			continue
+		}
+		if matchedPath == "" || len(rulePath) > len(matchedPath) {
+			format, matchedPath = rule.Format, rulePath
+		}
+	}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	if _, ok := formatExtensions[format]; !ok {
+		if format != "" && !cs.reportedFormats[format] {
+			cs.reportedFormats[format] = true
+			logger.Errorf("consul:storage", "unknown output format `%s`, writing `%s` files instead", format, consul.FormatEnv)
+		}
+		return consul.FormatEnv
+	}
+	return format
+}
+
+// formatFromFilePath returns output format of configuration file by its extension
+func formatFromFilePath(path string) string {
+	extension := filepath.Ext(path)
+	for format, formatExtension := range formatExtensions {
+		if formatExtension == extension {
+			return format
+		}
+	}
+	return consul.FormatEnv
+}
+
+// encodeEnv writes variables as `KEY="value"` lines, arrays are written as items joined by new line
+func encodeEnv(path string, variables []NamedVariable) []byte {
+	var fileLines []string
+	for _, variable := range variables {
+		if formattedValue, ok := formatScalar(variable.Value); ok {
+			fileLines = append(fileLines, variable.Name+"="+formattedValue)
+			continue
+		}
+		if text, ok := joinValue(variable.Value, "\n"); ok {
+			fileLines = append(fileLines, variable.Name+"="+strconv.Quote(text))
+		}
+	}
+	return []byte(joinLines(fileLines))
+}
+
+// encodeExport writes variables as `export KEY='value'` lines, which are safe to be sourced by shell
+func encodeExport(path string, variables []NamedVariable) []byte {
+	var fileLines []string
+	for _, variable := range variables {
+		if formattedValue, ok := formatScalar(variable.Value); ok {
+			fileLines = append(fileLines, "export "+variable.Name+"="+formattedValue)
+			continue
+		}
+		if text, ok := joinValue(variable.Value, "\n"); ok {
+			fileLines = append(fileLines, "export "+variable.Name+"='"+strings.ReplaceAll(text, "'", `'\''`)+"'")
+		}
+	}
+	return []byte(joinLines(fileLines))
+}
+
+// encodeJSON writes variables as JSON object with native values
+func encodeJSON(path string, variables []NamedVariable) []byte {
+	object := make(map[string]interface{}, len(variables))
+	for _, variable := range variables {
+		object[variable.Name] = variable.Value
+	}
+	return marshalJSON(object, "  ")
+}
+
+// encodeYAML writes variables as YAML mapping, strings are double-quoted and arrays use flow style
+func encodeYAML(path string, variables []NamedVariable) []byte {
+	var fileLines []string
+	for _, variable := range variables {
+		fileLines = append(fileLines, formatYAMLKey(variable.Name)+": "+formatFlowValue(variable.Value, "null"))
+	}
+	return []byte(joinLines(fileLines))
+}
+
+// encodeTOML writes variables as TOML key/value pairs with native values
+func encodeTOML(path string, variables []NamedVariable) []byte {
+	var fileLines []string
+	for _, variable := range variables {
+		fileLines = append(fileLines, formatKey(variable.Name)+" = "+formatFlowValue(variable.Value, `""`))
+	}
+	return []byte(joinLines(fileLines))
+}
+
+// encodeINI writes variables into section named after the file, multi-line values
+// (and array items) are written as indented continuation lines
+func encodeINI(path string, variables []NamedVariable) []byte {
+	section := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	fileLines := []string{"[" + section + "]"}
+	for _, variable := range variables {
+		if formattedValue, ok := formatScalar(variable.Value); ok {
+			fileLines = append(fileLines, variable.Name+" = "+formattedValue)
+			continue
+		}
+		text, ok := joinValue(variable.Value, "\n")
+		if !ok {
+			continue
+		}
+		if strings.TrimSpace(text) != text && !strings.Contains(text, "\n") {
+			text = strconv.Quote(text)
+		}
+		separator := " = "
+		if _, isArray := variable.Value.([]interface{}); isArray {
+			separator = " =\n"
+		}
+		fileLines = append(fileLines, strings.ReplaceAll(variable.Name+separator+text, "\n", "\n    "))
+	}
+	return []byte(joinLines(fileLines))
+}
+
+// encodeProperties writes variables as Java `.properties` file, arrays are written as comma-separated lists
+func encodeProperties(path string, variables []NamedVariable) []byte {
+	var fileLines []string
+	for _, variable := range variables {
+		formattedValue, ok := formatScalar(variable.Value)
+		if !ok {
+			var text string
+			if text, ok = joinValue(variable.Value, ","); !ok {
+				continue
+			}
+			formattedValue = escapeProperty(text, false)
+		}
+		fileLines = append(fileLines, escapeProperty(variable.Name, true)+"="+formattedValue)
+	}
+	return []byte(joinLines(fileLines))
+}
+
+// formatScalar formats booleans and numbers, which are written the same way in all formats
+func formatScalar(value interface{}) (string, bool) {
+	switch typedValue := value.(type) {
+	case bool:
+		return strconv.FormatBool(typedValue), true
+	case float32:
+		return strconv.FormatFloat(float64(typedValue), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), true
+	case int, int8, int16, int32, int64:
+		return fmt.Sprintf("%d", typedValue), true
+	}
+	return "", false
+}
+
+// joinValue converts string or array value into a string, array items are joined with separator
+func joinValue(value interface{}, separator string) (string, bool) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, true
+	case []interface{}:
+		var items []string
+		for _, item := range typedValue {
+			items = append(items, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(items, separator), true
+	}
+	return "", false
+}
+
+// formatFlowValue formats value in JSON-compatible flow style, which is valid both in YAML and TOML
+func formatFlowValue(value interface{}, null string) string {
+	if formattedValue, ok := formatScalar(value); ok {
+		return formattedValue
+	}
+	switch typedValue := value.(type) {
+	case string:
+		return string(bytes.TrimSpace(marshalJSON(typedValue, "")))
+	case []interface{}:
+		var items []string
+		for _, item := range typedValue {
+			items = append(items, formatFlowValue(item, null))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case nil:
+		return null
+	}
+	return formatFlowValue(fmt.Sprintf("%v", value), null)
+}
+
+// formatKey quotes key which cannot be written as bare key in YAML and TOML
+func formatKey(key string) string {
+	if bareKeyPattern.MatchString(key) {
+		return key
+	}
+	return formatFlowValue(key, "")
+}
+
+// formatYAMLKey quotes key which cannot be written as bare key in YAML, or which YAML would not read back as string
+func formatYAMLKey(key string) string {
+	if yamlResolvedKeyPattern.MatchString(key) {
+		return formatFlowValue(key, "")
+	}
+	return formatKey(key)
+}
+
+// marshalJSON encodes value as JSON without escaping HTML characters
+func marshalJSON(value interface{}, indent string) []byte {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(value); err != nil {
+		logger.Errorf("consul:storage", "failed to encode value to JSON - %s", err.Error())
+	}
+	return buffer.Bytes()
+}
+
+// escapeProperty escapes key or value of Java `.properties` file, non-ASCII characters are written as `\uXXXX`
+func escapeProperty(text string, isKey bool) string {
+	var builder strings.Builder
+	for index, character := range text {
+		switch {
+		case character == '\\':
+			builder.WriteString(`\\`)
+		case character == '\n':
+			builder.WriteString(`\n`)
+		case character == '\r':
+			builder.WriteString(`\r`)
+		case character == '\t':
+			builder.WriteString(`\t`)
+		case character == '\f':
+			builder.WriteString(`\f`)
+		case character == ' ' && (isKey || index == 0):
+			builder.WriteString(`\ `)
+		case isKey && strings.ContainsRune("=:#!", character):
+			builder.WriteRune('\\')
+			builder.WriteRune(character)
+		case character < 0x20 || character > 0x7e:
+			if high, low := utf16.EncodeRune(character); high != unicode.ReplacementChar {
+				builder.WriteString(fmt.Sprintf(`\u%04x\u%04x`, high, low))
+			} else {
+				builder.WriteString(fmt.Sprintf(`\u%04x`, character))
+			}
+		default:
+			builder.WriteRune(character)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestYAMLKeysAreReadBackAsStrings(t *testing.T) {
+	variables := []NamedVariable{
+		{Name: "on", Value: "a"},
+		{Name: "No", Value: "b"},
+		{Name: "null", Value: "c"},
+		{Name: "TRUE", Value: "d"},
+		{Name: "y", Value: "e"},
+		{Name: "1234", Value: "f"},
+		{Name: "1_000", Value: "g"},
+		{Name: "0x1F", Value: "h"},
+		{Name: "-1", Value: "i"},
+		{Name: "db.host", Value: "j"},
+		{Name: "plain_key", Value: "k"},
+	}
+	content := encodeYAML("app.yaml", variables)
+
+	decoded := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(content, &decoded); err != nil {
+		t.Fatalf("failed to decode YAML - %s\n%s", err.Error(), content)
+	}
+	for _, variable := range variables {
+		if value, ok := decoded[variable.Name]; !ok || value != variable.Value {
+			t.Fatalf("key `%s` was not read back as string, got %#v\n%s", variable.Name, decoded, content)
+		}
+	}
+	if !strings.Contains(string(content), "plain_key: ") {
+		t.Fatalf("expected plain key to be written without quotes\n%s", content)
+	}
+}
+
+func TestYAMLValues(t *testing.T) {
+	content := string(encodeYAML("app.yaml", []NamedVariable{
+		{Name: "enabled", Value: true},
+		{Name: "port", Value: float64(8080)},
+		{Name: "mode", Value: "on"},
+		{Name: "hosts", Value: []interface{}{"a", "b"}},
+	}))
+	for _, line := range []string{`enabled: true`, `port: 8080`, `mode: "on"`, `hosts: ["a", "b"]`} {
+		if !strings.Contains(content, line) {
+			t.Fatalf("expected `%s` in\n%s", line, content)
+		}
+	}
+}
+
+func TestTOMLKeys(t *testing.T) {
+	content := string(encodeTOML("app.toml", []NamedVariable{
+		{Name: "on", Value: "a"},
+		{Name: "db.host", Value: "b"},
+	}))
+	for _, line := range []string{`on = "a"`, `"db.host" = "b"`} {
+		if !strings.Contains(content, line) {
+			t.Fatalf("expected `%s` in\n%s", line, content)
+		}
+	}
+}
+
+func TestFormatFromFilePath(t *testing.T) {
+	tests := map[string]string{
+		"app/main.env":        "env",
+		"app/main.yml":        "yaml",
+		"app/main.json":       "json",
+		"app/main.properties": "properties",
+	}
+	for path, expected := range tests {
+		if format := formatFromFilePath(path); format != expected {
+			t.Fatalf("expected format `%s` for `%s`, got `%s`", expected, path, format)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/leads-su/broker"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	p "github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+)
+
+// newTestStorage creates storage which writes files to temporary directory, along with parser it reads values from
+func newTestStorage(t *testing.T) (*ConsulStorage, *p.Parser) {
+	t.Helper()
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Agent.Network.Address = "127.0.0.1"
+	config.Consul.WriteTo = t.TempDir()
+	config.Consul.Mapping.Owner = ""
+	config.Consul.Mapping.Group = ""
+	parser := p.NewParser(config)
+	consulStorage := NewStorage(config, parser)
+	parser.SetFileResolver(consulStorage.ConfigurationFilePath)
+	return consulStorage, parser
+}
+
+// pair creates Consul pair with given raw value
+func pair(key, value string) *api.KVPair {
+	return &api.KVPair{Key: key, Value: []byte(value), ModifyIndex: 1}
+}
+
+// process processes snapshot of pairs and writes configuration files
+func process(consulStorage *ConsulStorage, parser *p.Parser, pairs ...*api.KVPair) {
+	parser.ProcessReceivedData(pairs)
+	consulStorage.ProcessChanges(parser.GenerateConfiguration())
+}
+
+// readFile returns contents of the file under `consul.write_to`, failing the test when file cannot be read
+func readFile(t *testing.T, consulStorage *ConsulStorage, path string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(filepath.Join(consulStorage.config.Consul.WriteTo, path))
+	if err != nil {
+		t.Fatalf("failed to read `%s` - %s", path, err.Error())
+	}
+	return string(content)
+}
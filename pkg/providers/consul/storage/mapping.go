@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+	"github.com/leads-su/logger"
+)
+
+// mappingPlaceholderPattern matches `{name}` and `{N}` placeholders of file path patterns
+var mappingPlaceholderPattern = regexp.MustCompile(`\{(name|[0-9]+)\}`)
+
+// mapKeyToFile returns path (relative to `consul.write_to`, without extension) of the file key under given Consul path
+// is written to, empty path is returned for keys which should not be written to any file
+func (cs *ConsulStorage) mapKeyToFile(keyPath string) (string, error) {
+	mapping := cs.mappingOptions()
+	segments := strings.Split(keyPath, "/")
+	if cs.isIgnoredKey(mapping, segments) {
+		return "", nil
+	}
+
+	depth, filename := mapping.Depth, mapping.Filename
+	rule := cs.matchMappingRule(mapping, keyPath)
+	switch {
+	case rule != nil && rule.File != "":
+		filePath, err := expandMappingPattern(rule.File, segments, "")
+		if err != nil {
+			return "", err
+		}
+		return validateFilePath(keyPath, filePath)
+	case rule != nil:
+		if rule.Depth > 0 {
+			depth = rule.Depth
+		}
+		if rule.Filename != "" {
+			filename = rule.Filename
+		}
+	case mapping.Unmatched == consul.MappingUnmatchedSkip:
+		return "", nil
+	}
+
+	if depth < 1 {
+		depth = 1
+	}
+	if depth > len(segments) {
+		depth = len(segments)
+	}
+	if filename == "" {
+		filename = "{name}"
+	}
+	name, err := expandMappingPattern(filename, segments, strings.ToLower(segments[depth-1]))
+	if err != nil {
+		return "", err
+	}
+	directories := strings.ToLower(strings.Join(segments[:depth-1], "/"))
+	if directories != "" {
+		name = directories + "/" + name
+	}
+	return validateFilePath(keyPath, name)
+}
+
+// matchMappingRule returns mapping rule with the longest prefix matching given key path
+func (cs *ConsulStorage) matchMappingRule(mapping *consul.Mapping, keyPath string) *consul.MappingRule {
+	var matchedRule *consul.MappingRule
+	matchedPrefix := ""
+	for _, rule := range mapping.Rules {
+		if rule == nil {
+			continue
+		}
+		prefix := strings.Trim(rule.Prefix, "/")
+		if prefix == "" || (keyPath != prefix && !strings.HasPrefix(keyPath, prefix+"/")) {
+			continue
+		}
+		if matchedRule == nil || len(prefix) > len(matchedPrefix) {
+			matchedRule, matchedPrefix = rule, prefix
+		}
+	}
+	return matchedRule
+}
+
+// isIgnoredKey reports whether key path (or any of its parent paths) matches one of the ignore patterns
+func (cs *ConsulStorage) isIgnoredKey(mapping *consul.Mapping, segments []string) bool {
+	for _, pattern := range mapping.Ignore {
+		pattern = strings.Trim(pattern, "/")
+		for length := 1; length <= len(segments); length++ {
+			if matched, err := path.Match(pattern, strings.Join(segments[:length], "/")); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mappingOptions returns configured mapping options, falling back to two leading segments of the key path
+func (cs *ConsulStorage) mappingOptions() *consul.Mapping {
+	if cs.config.Consul.Mapping == nil {
+		return &consul.Mapping{Depth: 2, Filename: "{name}", Unmatched: consul.MappingUnmatchedDefault}
+	}
+	return cs.config.Consul.Mapping
+}
+
+// reportMappingError logs and reports key which cannot be written to any file, every error is only reported once
+func (cs *ConsulStorage) reportMappingError(message string) {
+	if cs.reportedMappings[message] {
+		return
+	}
+	cs.reportedMappings[message] = true
+	logger.Error("consul:storage", message)
+	cs.sendErrorNotification(message)
+}
+
+// expandMappingPattern replaces `{name}` placeholder with given name and `{N}` with lower-cased N-th segment of the key path
+func expandMappingPattern(pattern string, segments []string, name string) (string, error) {
+	var err error
+	expanded := mappingPlaceholderPattern.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		argument := strings.Trim(placeholder, "{}")
+		if argument == "name" {
+			return name
+		}
+		index, _ := strconv.Atoi(argument)
+		if index < 1 || index > len(segments) {
+			err = fmt.Errorf("pattern `%s` refers to segment %d, but key has %d segments", pattern, index, len(segments))
+			return placeholder
+		}
+		return strings.ToLower(segments[index-1])
+	})
+	return expanded, err
+}
+
+// validateFilePath makes sure file path stays inside `consul.write_to` directory
+func validateFilePath(keyPath, filePath string) (string, error) {
+	cleanPath := filepath.Clean(filepath.FromSlash(filePath))
+	if filepath.IsAbs(cleanPath) || cleanPath == "." || cleanPath == ".." || strings.HasPrefix(cleanPath, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("file path `%s` of `%s` escapes the configuration directory", filePath, keyPath)
+	}
+	return cleanPath, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+func TestKeysAreMappedToFiles(t *testing.T) {
+	consulStorage, _ := newTestStorage(t)
+	consulStorage.config.Consul.Mapping = &consul.Mapping{
+		Depth:     2,
+		Filename:  "{name}",
+		Unmatched: consul.MappingUnmatchedDefault,
+		Ignore:    []string{"secrets", "*/internal"},
+		Rules: []*consul.MappingRule{
+			{Prefix: "services", Depth: 3, Filename: "{2}-{name}"},
+			{Prefix: "services/legacy", File: "legacy/{3}"},
+			{Prefix: "escape", File: "../{2}"},
+			{Prefix: "missing", File: "{5}"},
+		},
+	}
+	cases := map[string]string{
+		"App/DB/host":              "app/db",
+		"app/port":                 "app/port",
+		"app":                      "app",
+		"services/api/db/host":     "services/api/api-db",
+		"services/legacy/web/host": "legacy/web",
+		"secrets/db/password":      "",
+		"app/internal/token":       "",
+	}
+	for keyPath, expected := range cases {
+		filePath, err := consulStorage.mapKeyToFile(keyPath)
+		if err != nil || filePath != filepath.FromSlash(expected) {
+			t.Fatalf("expected `%s` to be mapped to `%s`, got `%s` (%v)", keyPath, expected, filePath, err)
+		}
+	}
+	for _, keyPath := range []string{"escape/etc/passwd", "missing/key"} {
+		if filePath, err := consulStorage.mapKeyToFile(keyPath); err == nil {
+			t.Fatalf("expected mapping of `%s` to fail, got `%s`", keyPath, filePath)
+		}
+	}
+}
+
+func TestUnmatchedKeysAreSkipped(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.config.Consul.Mapping.Unmatched = consul.MappingUnmatchedSkip
+	consulStorage.config.Consul.Mapping.Rules = []*consul.MappingRule{{Prefix: "app", File: "application"}}
+	process(consulStorage, parser,
+		pair("app/db/host", `{"type":"string","value":"h"}`),
+		pair("worker/db/host", `{"type":"string","value":"w"}`),
+	)
+
+	if content := readFile(t, consulStorage, "application.env"); !strings.Contains(content, `CONSUL_APP_DB_HOST="h"`) {
+		t.Fatalf("expected key matching the rule to be written, got:\n%s", content)
+	}
+	if _, err := os.Stat(filepath.Join(consulStorage.config.Consul.WriteTo, "worker")); !os.IsNotExist(err) {
+		t.Fatalf("expected unmatched key not to be written")
+	}
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+func TestVariablesAreWrittenUnderConfiguredNames(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.config.Consul.Naming = &consul.Naming{Prefix: "APP_", StripSegments: 1}
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+
+	content := readFile(t, consulStorage, "app/db.env")
+	if !strings.Contains(content, `APP_DB_HOST="h"`) || strings.Contains(content, "CONSUL_") {
+		t.Fatalf("expected variable to be written under configured name, got:\n%s", content)
+	}
+}
+
+func TestCollidingVariableNamesKeepFirstKey(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.config.Consul.Naming = &consul.Naming{
+		Prefix: "CONSUL_",
+		Rules:  []*consul.NamingRule{{Path: "app/db/legacy", Name: "CONSUL_APP_DB"}},
+	}
+	process(consulStorage, parser,
+		pair("app/db/host", `{"type":"string","value":"current"}`),
+		pair("app/db/legacy/host", `{"type":"string","value":"legacy"}`),
+	)
+
+	content := readFile(t, consulStorage, "app/db.env")
+	if strings.Count(content, "CONSUL_APP_DB_HOST=") != 1 || !strings.Contains(content, `CONSUL_APP_DB_HOST="current"`) {
+		t.Fatalf("expected only the first key to be written, got:\n%s", content)
+	}
+}
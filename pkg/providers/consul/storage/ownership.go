@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"os/user"
+	"strconv"
+)
+
+// lookupOwnership returns identifiers of the user and group, -1 is returned for empty names (so they are not changed),
+// primary group of the user is used when only user is given
+func lookupOwnership(userName, groupName string) (int, int, error) {
+	uid, gid := -1, -1
+	if userName != "" {
+		userInformation, err := user.Lookup(userName)
+		if err != nil {
+			return 0, 0, err
+		}
+		if uid, err = strconv.Atoi(userInformation.Uid); err != nil {
+			return 0, 0, err
+		}
+		if gid, err = strconv.Atoi(userInformation.Gid); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if groupName != "" {
+		groupInformation, err := user.LookupGroup(groupName)
+		if err != nil {
+			return 0, 0, err
+		}
+		if gid, err = strconv.Atoi(groupInformation.Gid); err != nil {
+			return 0, 0, err
+		}
+	}
+	return uid, gid, nil
+}
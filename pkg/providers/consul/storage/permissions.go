@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	p "github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+	"github.com/leads-su/logger"
+)
+
+// filePermissions describes mode and ownership of written file and directories it is placed in
+type filePermissions struct {
+	// Mode is a permission mode of the file
+	Mode os.FileMode
+
+	// DirectoryMode is a permission mode of directories between `consul.write_to` and the file, zero leaves them untouched
+	DirectoryMode os.FileMode
+
+	// Owner and Group own the file, user CCM runs as is used when empty
+	Owner string
+	Group string
+
+	// DirectoryOwner and DirectoryGroup own directories, they are only set by `consul.mapping`, as directories are shared between files
+	DirectoryOwner string
+	DirectoryGroup string
+}
+
+// appliedPermissions describes mode and ownership which were applied to the file when it was written
+type appliedPermissions struct {
+	Mode os.FileMode
+	UID  int
+	GID  int
+}
+
+// configurationPermissions resolves permissions of configuration file, `consul.mapping` settings are overridden
+// by the rule matching the first key (by Consul path) and then by `mode`, `owner` and `group` of the values,
+// when several values request mode, the most restrictive combination of them is used
+func (cs *ConsulStorage) configurationPermissions(variables []NamedVariable) *filePermissions {
+	mapping := cs.mappingOptions()
+	permissions := &filePermissions{
+		Mode:          cs.parseMappingMode(mapping.Mode, 0640),
+		DirectoryMode: cs.parseMappingMode(mapping.DirectoryMode, 0750),
+		Owner:         mapping.Owner,
+		Group:         mapping.Group,
+	}
+
+	sourceSet := make(map[string]bool)
+	for _, variable := range variables {
+		if source, err := cs.parser.GetReferenceStorage().GetSource(variable.Key); err == nil {
+			sourceSet[strings.Trim(source, "/")] = true
+		}
+	}
+	sources := make([]string, 0, len(sourceSet))
+	for source := range sourceSet {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	if len(sources) != 0 {
+		if rule := cs.matchMappingRule(mapping, sources[0]); rule != nil {
+			permissions.Mode = cs.parseMappingMode(rule.Mode, permissions.Mode)
+			permissions.DirectoryMode = cs.parseMappingMode(rule.DirectoryMode, permissions.DirectoryMode)
+			if rule.Owner != "" {
+				permissions.Owner = rule.Owner
+			}
+			if rule.Group != "" {
+				permissions.Group = rule.Group
+			}
+		}
+	}
+	permissions.DirectoryOwner, permissions.DirectoryGroup = permissions.Owner, permissions.Group
+
+	var requestedMode os.FileMode
+	ownerRequested, groupRequested := false, false
+	for _, source := range sources {
+		requested, ok := cs.parser.FilePermissions(source)
+		if !ok {
+			continue
+		}
+		if requested.Mode != 0 {
+			if requestedMode == 0 {
+				requestedMode = requested.Mode
+			} else {
+				requestedMode &= requested.Mode
+			}
+		}
+		if requested.Owner != "" && !ownerRequested {
+			permissions.Owner, ownerRequested = requested.Owner, true
+		}
+		if requested.Group != "" && !groupRequested {
+			permissions.Group, groupRequested = requested.Group, true
+		}
+	}
+	if requestedMode != 0 {
+		permissions.Mode = requestedMode
+	}
+	return permissions
+}
+
+// fileValuePermissions returns permissions of file value, directories are created with `consul.mapping` directory mode
+func (cs *ConsulStorage) fileValuePermissions(file *p.FileValue) *filePermissions {
+	mapping := cs.mappingOptions()
+	permissions := &filePermissions{
+		Mode:           file.Mode,
+		DirectoryMode:  cs.parseMappingMode(mapping.DirectoryMode, 0750),
+		Owner:          file.Owner,
+		DirectoryOwner: mapping.Owner,
+		DirectoryGroup: mapping.Group,
+	}
+	if parts := strings.SplitN(file.Owner, ":", 2); len(parts) == 2 {
+		permissions.Owner, permissions.Group = parts[0], parts[1]
+	}
+	return permissions
+}
+
+// parseMappingMode parses permission mode from `consul.mapping` settings, fallback is used for empty or invalid mode
+func (cs *ConsulStorage) parseMappingMode(mode string, fallback os.FileMode) os.FileMode {
+	if mode == "" {
+		return fallback
+	}
+	parsedMode, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		cs.reportMappingError(fmt.Sprintf("invalid mode `%s` in `consul.mapping`, using %04o instead", mode, fallback))
+		return fallback
+	}
+	return os.FileMode(parsedMode)
+}
+
+// applyOwnership changes owner and group of the file or directory, nothing is changed when neither is set
+func applyOwnership(path, owner, group string) error {
+	if owner == "" && group == "" {
+		return nil
+	}
+	uid, gid, err := lookupOwnership(owner, group)
+	if err != nil {
+		return err
+	}
+	return os.Chown(path, uid, gid)
+}
+
+// applyDirectoryPermissions applies directory mode and ownership to every directory between `consul.write_to` and the file
+func (cs *ConsulStorage) applyDirectoryPermissions(path string, permissions *filePermissions) {
+	if permissions.DirectoryMode == 0 || filepath.IsAbs(path) {
+		return
+	}
+	for directory := filepath.Dir(path); directory != "." && directory != string(os.PathSeparator); directory = filepath.Dir(directory) {
+		absolutePath := cs.storage.AbsolutePath(directory)
+		err := os.Chmod(absolutePath, permissions.DirectoryMode|os.ModeDir)
+		if err == nil {
+			err = applyOwnership(absolutePath, permissions.DirectoryOwner, permissions.DirectoryGroup)
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("failed to change permissions of directory `%s` - %s", directory, err.Error())
+			logger.Error("consul:storage", errMsg)
+			cs.sendErrorNotification(errMsg)
+			return
+		}
+	}
+}
+
+// recordPermissions remembers mode and ownership of the file, so they can be checked for drift before the next write
+func (cs *ConsulStorage) recordPermissions(path string) {
+	fileStorage := cs.storageFor(path)
+	info, err := os.Stat(fileStorage.AbsolutePath(path))
+	if err != nil {
+		return
+	}
+	applied := &appliedPermissions{Mode: info.Mode().Perm(), UID: -1, GID: -1}
+	if uid, gid, ok := fileOwnership(info); ok {
+		applied.UID, applied.GID = uid, gid
+	}
+	cs.appliedPermissions[path] = applied
+	delete(cs.reportedDrift, path)
+}
+
+// CheckPermissions reports and restores mode and ownership of written files which were changed outside of CCM
+func (cs *ConsulStorage) CheckPermissions() {
+	cs.Lock()
+	defer cs.Unlock()
+	paths := make([]string, 0, len(cs.appliedPermissions))
+	for path := range cs.appliedPermissions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if cs.reportPermissionsDrift(path) {
+			cs.restorePermissions(path)
+		}
+	}
+}
+
+// restorePermissions applies mode and ownership the file had when it was written by CCM
+func (cs *ConsulStorage) restorePermissions(path string) {
+	applied := cs.appliedPermissions[path]
+	absolutePath := cs.storageFor(path).AbsolutePath(path)
+	err := os.Chmod(absolutePath, applied.Mode)
+	if err == nil && (applied.UID >= 0 || applied.GID >= 0) {
+		err = os.Chown(absolutePath, applied.UID, applied.GID)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to restore permissions of `%s` - %s", path, err.Error())
+		logger.Error("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+		return
+	}
+	delete(cs.reportedDrift, path)
+}
+
+// reportPermissionsDrift reports file which mode or ownership were changed since it was written by CCM,
+// the same drift is only reported once, caller is responsible for restoring permissions
+func (cs *ConsulStorage) reportPermissionsDrift(path string) bool {
+	applied, ok := cs.appliedPermissions[path]
+	if !ok {
+		return false
+	}
+	fileStorage := cs.storageFor(path)
+	info, err := os.Stat(fileStorage.AbsolutePath(path))
+	if err != nil {
+		return false
+	}
+
+	var drift []string
+	if info.Mode().Perm() != applied.Mode {
+		drift = append(drift, fmt.Sprintf("mode is %04o instead of %04o", info.Mode().Perm(), applied.Mode))
+	}
+	if uid, gid, ok := fileOwnership(info); ok {
+		if applied.UID >= 0 && uid != applied.UID {
+			drift = append(drift, fmt.Sprintf("owner is %d instead of %d", uid, applied.UID))
+		}
+		if applied.GID >= 0 && gid != applied.GID {
+			drift = append(drift, fmt.Sprintf("group is %d instead of %d", gid, applied.GID))
+		}
+	}
+	if len(drift) == 0 {
+		delete(cs.reportedDrift, path)
+		return false
+	}
+	errMsg := fmt.Sprintf("permissions of `%s` were changed outside of CCM (%s), restoring them", path, strings.Join(drift, ", "))
+	if cs.reportedDrift[path] != errMsg {
+		cs.reportedDrift[path] = errMsg
+		logger.Warn("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+	}
+	return true
+}
+
+// defaultPermissions returns permissions of configuration file which has no values left
+func (cs *ConsulStorage) defaultPermissions(path string) *filePermissions {
+	permissions := cs.configurationPermissions(nil)
+	if applied, ok := cs.appliedPermissions[path]; ok {
+		permissions.Mode = applied.Mode
+	}
+	return permissions
+}
@@ -0,0 +1,103 @@
+//go:build !windows
+// +build !windows
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+// fileMode returns permission mode of the file under `consul.write_to`, failing the test when file does not exist
+func fileMode(t *testing.T, consulStorage *ConsulStorage, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(filepath.Join(consulStorage.config.Consul.WriteTo, path))
+	if err != nil {
+		t.Fatalf("failed to stat `%s` - %s", path, err.Error())
+	}
+	return info.Mode().Perm()
+}
+
+func TestConfigurationFilesAreWrittenWithMappingModes(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.config.Consul.Mapping.Rules = []*consul.MappingRule{{Prefix: "secure", Mode: "0600", DirectoryMode: "0700"}}
+	process(consulStorage, parser,
+		pair("app/db/host", `{"type":"string","value":"h"}`),
+		pair("secure/db/password", `{"type":"string","value":"p"}`),
+	)
+
+	expected := map[string]os.FileMode{
+		"app/db.env":    0640,
+		"app":           0750,
+		"secure/db.env": 0600,
+		"secure":        0700,
+	}
+	for path, mode := range expected {
+		if actual := fileMode(t, consulStorage, path); actual != mode {
+			t.Fatalf("expected `%s` to have mode %04o, got %04o", path, mode, actual)
+		}
+	}
+}
+
+func TestMostRestrictiveModeRequestedByValuesIsUsed(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	process(consulStorage, parser,
+		pair("app/db/host", `{"type":"string","value":"h","mode":"0644"}`),
+		pair("app/db/password", `{"type":"string","value":"p","mode":"0604"}`),
+		pair("app/db/port", `{"type":"number","value":5432,"mode":"invalid"}`),
+	)
+
+	if mode := fileMode(t, consulStorage, "app/db.env"); mode != 0604 {
+		t.Fatalf("expected mode 0604, got %04o", mode)
+	}
+	if content := readFile(t, consulStorage, "app/db.env"); strings.Contains(content, "CONSUL_APP_DB_PORT") {
+		t.Fatalf("expected value with invalid mode not to be written, got:\n%s", content)
+	}
+}
+
+func TestUnchangedFileIsNotReplaced(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+
+	path := filepath.Join(consulStorage.config.Consul.WriteTo, "app/db.env")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatalf("failed to change modification time - %s", err.Error())
+	}
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+	if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(past) {
+		t.Fatalf("expected unchanged file not to be replaced")
+	}
+
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"changed"}`))
+	if content := readFile(t, consulStorage, "app/db.env"); !strings.Contains(content, `CONSUL_APP_DB_HOST="changed"`) {
+		t.Fatalf("expected changed file to be replaced, got:\n%s", content)
+	}
+}
+
+func TestChangedPermissionsAreRestored(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+
+	path := filepath.Join(consulStorage.config.Consul.WriteTo, "app/db.env")
+	if err := os.Chmod(path, 0666); err != nil {
+		t.Fatalf("failed to change mode - %s", err.Error())
+	}
+	consulStorage.CheckPermissions()
+	if mode := fileMode(t, consulStorage, "app/db.env"); mode != 0640 {
+		t.Fatalf("expected mode to be restored to 0640, got %04o", mode)
+	}
+
+	if err := os.Chmod(path, 0666); err != nil {
+		t.Fatalf("failed to change mode - %s", err.Error())
+	}
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+	if mode := fileMode(t, consulStorage, "app/db.env"); mode != 0640 {
+		t.Fatalf("expected mode to be restored by the next write, got %04o", mode)
+	}
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	consulAPI "github.com/hashicorp/consul/api"
+	"github.com/leads-su/logger"
+)
+
+const (
+	// serviceWaitTime is the longest time blocking query waits for changes of the service
+	serviceWaitTime = 5 * time.Minute
+	// serviceRetryDelay is a delay before failed query is retried
+	serviceRetryDelay = 5 * time.Second
+)
+
+// errServicesPending is returned by `service` template function until the first query of the service completes
+var errServicesPending = errors.New("instances of the service are not loaded yet")
+
+// serviceWatch describes blocking query which keeps healthy instances of the service up to date
+type serviceWatch struct {
+	name     string
+	tag      string
+	services []*TemplateService
+	err      error
+	ready    bool
+	touched  bool
+	cancel   context.CancelFunc
+}
+
+// ServicesChanged returns channel which receives value whenever instances of services used by templates change
+func (cs *ConsulStorage) ServicesChanged() <-chan bool {
+	return cs.servicesChanged
+}
+
+// RenderTemplates renders every configured template which source or inputs changed since its last render
+func (cs *ConsulStorage) RenderTemplates() {
+	cs.Lock()
+	defer cs.Unlock()
+	cs.renderTemplates()
+}
+
+// StopServiceWatches stops queries of every service used by templates
+func (cs *ConsulStorage) StopServiceWatches() {
+	cs.servicesLock.Lock()
+	defer cs.servicesLock.Unlock()
+	for identifier, watch := range cs.serviceWatches {
+		watch.cancel()
+		delete(cs.serviceWatches, identifier)
+	}
+}
+
+// cachedServices returns healthy instances of the service from the cache, starting query of the service
+// when it is requested for the first time
+func (cs *ConsulStorage) cachedServices(name, tag string) ([]*TemplateService, error) {
+	if cs.serviceCatalog == nil {
+		return nil, fmt.Errorf("service catalog is not available")
+	}
+	cs.servicesLock.Lock()
+	defer cs.servicesLock.Unlock()
+	identifier := name + "\x00" + tag
+	watch, exists := cs.serviceWatches[identifier]
+	if !exists {
+		ctx, cancel := context.WithCancel(context.Background())
+		watch = &serviceWatch{name: name, tag: tag, cancel: cancel}
+		cs.serviceWatches[identifier] = watch
+		go cs.watchService(ctx, cs.serviceCatalog, watch)
+	}
+	watch.touched = true
+	if !watch.ready {
+		return nil, errServicesPending
+	}
+	return watch.services, watch.err
+}
+
+// pruneServiceWatches stops queries of services which were not used by any template during the last render
+func (cs *ConsulStorage) pruneServiceWatches() {
+	cs.servicesLock.Lock()
+	defer cs.servicesLock.Unlock()
+	for identifier, watch := range cs.serviceWatches {
+		if !watch.touched {
+			watch.cancel()
+			delete(cs.serviceWatches, identifier)
+			continue
+		}
+		watch.touched = false
+	}
+}
+
+// watchService runs blocking queries of the service until watch is stopped, templates are re-rendered
+// whenever the list of healthy instances changes
+func (cs *ConsulStorage) watchService(ctx context.Context, catalog ServiceCatalog, watch *serviceWatch) {
+	var waitIndex uint64
+	for {
+		options := (&consulAPI.QueryOptions{WaitIndex: waitIndex, WaitTime: serviceWaitTime}).WithContext(ctx)
+		entries, meta, err := catalog.Service(watch.name, watch.tag, true, options)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warnf("consul:storage", "failed to query instances of service `%s` - %s", watch.name, err.Error())
+			cs.updateServiceWatch(watch, nil, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(serviceRetryDelay):
+			}
+			continue
+		}
+
+		// Index going backwards means that Consul state was reset, query should start from scratch
+		if meta.LastIndex < waitIndex {
+			waitIndex = 0
+		} else {
+			waitIndex = meta.LastIndex
+		}
+		cs.updateServiceWatch(watch, templateServices(entries), nil)
+	}
+}
+
+// updateServiceWatch stores result of the query, last known instances are kept when query of already loaded service fails
+func (cs *ConsulStorage) updateServiceWatch(watch *serviceWatch, services []*TemplateService, err error) {
+	cs.servicesLock.Lock()
+	if err != nil && watch.ready && watch.err == nil {
+		cs.servicesLock.Unlock()
+		return
+	}
+	changed := !watch.ready || !reflect.DeepEqual(watch.services, services) || fmt.Sprint(watch.err) != fmt.Sprint(err)
+	watch.services, watch.err, watch.ready = services, err, true
+	cs.servicesLock.Unlock()
+
+	if changed {
+		select {
+		case cs.servicesChanged <- true:
+		default:
+		}
+	}
+}
+
+// templateServices converts service entries to instances seen by templates, sorted by ID
+func templateServices(entries []*consulAPI.ServiceEntry) []*TemplateService {
+	services := make([]*TemplateService, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		services = append(services, &TemplateService{
+			ID:      entry.Service.ID,
+			Name:    entry.Service.Service,
+			Address: address,
+			Port:    entry.Service.Port,
+			Tags:    entry.Service.Tags,
+			Meta:    entry.Service.Meta,
+		})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ID < services[j].ID
+	})
+	return services
+}
@@ -1,20 +1,18 @@
 package storage
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
 	cfg "github.com/leads-su/consul-config-manager/pkg/config"
-	"github.com/leads-su/consul-config-manager/pkg/config/notifier"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
 	p "github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
 	"github.com/leads-su/logger"
 	notifierPackage "github.com/leads-su/notifier"
 	s "github.com/leads-su/storage"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
-	"text/template"
 )
 
 type ConsulStorage struct {
@@ -25,8 +23,62 @@ type ConsulStorage struct {
 	// storage is an instance of storage
 	storage *s.Storage
 
+	// rootStorage is an instance of storage used for files with absolute path (e.g. rendered templates)
+	rootStorage *s.Storage
+
 	// parser is an instance of parser
 	parser *p.Parser
+
+	// writtenFiles is a list of files written during last processing of changes
+	writtenFiles map[string]bool
+
+	// fileValuePaths is a list of paths of written file values, indexed by key
+	fileValuePaths map[string]string
+
+	// reportedCollisions is a list of variable name collisions which were already reported
+	reportedCollisions map[string]bool
+
+	// appliedPermissions is a list of mode and ownership of written files, used to detect changes made by hand
+	appliedPermissions map[string]*appliedPermissions
+
+	// reportedDrift is the last reported permissions drift of every file, indexed by file path
+	reportedDrift map[string]string
+
+	// reportedMappings is a list of keys which cannot be mapped to files, that were already reported
+	reportedMappings map[string]bool
+
+	// reportedFormats is a list of unknown output formats which were already reported
+	reportedFormats map[string]bool
+
+	// reportedUnits is a list of unit conversion errors which were already reported
+	reportedUnits map[string]bool
+
+	// writeHandler is called with path of every file which was written or removed
+	writeHandler func(path string)
+
+	// candidateValidator checks candidate version of every file before it is moved into place
+	candidateValidator CandidateValidator
+
+	// reportedRejections is the last reported rejection of every held back file, indexed by file path
+	reportedRejections map[string]string
+
+	// serviceCatalog is a catalog of services available to templates
+	serviceCatalog ServiceCatalog
+
+	// servicesLock guards serviceWatches, which are updated by queries running outside of the storage lock
+	servicesLock sync.Mutex
+
+	// serviceWatches is a list of queries of services used by templates, indexed by service name and tag
+	serviceWatches map[string]*serviceWatch
+
+	// servicesChanged receives value whenever instances of services used by templates change
+	servicesChanged chan bool
+
+	// renderedTemplates is a list of the last successful renders of templates, indexed by destination
+	renderedTemplates map[string]*renderedTemplate
+
+	// reportedTemplateErrors is the last reported error of every template, indexed by destination
+	reportedTemplateErrors map[string]string
 }
 
 // NewStorage create new Consul storage instance
@@ -36,10 +88,33 @@ func NewStorage(config *cfg.Config, parser *p.Parser) *ConsulStorage {
 		storage: s.NewStorage(s.Options{
 			WorkingDirectory: config.Consul.WriteTo,
 		}),
-		parser: parser,
+		rootStorage: s.NewStorage(s.Options{
+			WorkingDirectory: "",
+		}),
+		parser:                 parser,
+		writtenFiles:           make(map[string]bool),
+		fileValuePaths:         make(map[string]string),
+		appliedPermissions:     make(map[string]*appliedPermissions),
+		reportedDrift:          make(map[string]string),
+		reportedCollisions:     make(map[string]bool),
+		reportedFormats:        make(map[string]bool),
+		reportedUnits:          make(map[string]bool),
+		reportedMappings:       make(map[string]bool),
+		renderedTemplates:      make(map[string]*renderedTemplate),
+		reportedTemplateErrors: make(map[string]string),
+		reportedRejections:     make(map[string]string),
+		serviceWatches:         make(map[string]*serviceWatch),
+		servicesChanged:        make(chan bool, 1),
 	}
 }
 
+// CandidateValidator checks candidate version of the file (written to candidatePath) before it replaces the previous one,
+// it is implemented by `validator.Validator`
+type CandidateValidator interface {
+	Validate(path string, candidatePath string) error
+	Release(path string)
+}
+
 type ConfigContent = map[string]interface{}
 type Configs = map[string]ConfigContent
 
@@ -47,145 +122,433 @@ type Configs = map[string]ConfigContent
 func (cs *ConsulStorage) ProcessChanges(changes map[string]interface{}) {
 	cs.Lock()
 	defer cs.Unlock()
+	configs, files := cs.groupChanges(changes)
+	writtenFiles := make(map[string]bool)
+	fileValuePaths := make(map[string]string)
+	for key, fileValue := range files {
+		cs.writeFileValue(fileValue)
+		writtenFiles[fileValue.Path] = true
+		fileValuePaths[key] = fileValue.Path
+	}
+	for path, variables := range configs {
+		cs.writeToFile(path, variables)
+		writtenFiles[path] = true
+	}
+	for path := range cs.writtenFiles {
+		if !writtenFiles[path] {
+			cs.processEmptyFile(path)
+		}
+	}
+	cs.writtenFiles = writtenFiles
+	cs.fileValuePaths = fileValuePaths
+	cs.renderTemplates()
+}
+
+// ProcessChangedKeys processes changes retrieved from Consul, rewriting only files which contain one of given keys
+func (cs *ConsulStorage) ProcessChangedKeys(changes map[string]interface{}, keys []string) {
+	cs.Lock()
+	defer cs.Unlock()
+	affectedFiles := make(map[string]bool)
+	for _, key := range keys {
+		// File of the value which was removed, expired or moved to another path has to be processed as well
+		if previousPath, ok := cs.fileValuePaths[key]; ok {
+			affectedFiles[previousPath] = true
+			delete(cs.fileValuePaths, key)
+		}
+		if fileValue, ok := changes[key].(*p.FileValue); ok {
+			affectedFiles[fileValue.Path] = true
+			continue
+		}
+		if configPath := cs.generateConfigurationFilePath(key); configPath != "" {
+			affectedFiles[configPath] = true
+		}
+	}
+
+	configs, files := cs.groupChanges(changes)
+	fileValuesWritten := make(map[string]bool)
+	for key, fileValue := range files {
+		if affectedFiles[fileValue.Path] {
+			cs.writeFileValue(fileValue)
+			cs.writtenFiles[fileValue.Path] = true
+			cs.fileValuePaths[key] = fileValue.Path
+			fileValuesWritten[fileValue.Path] = true
+		}
+	}
+	for path := range affectedFiles {
+		if fileValuesWritten[path] {
+			continue
+		}
+		if variables, ok := configs[path]; ok {
+			cs.writeToFile(path, variables)
+			cs.writtenFiles[path] = true
+		} else if cs.writtenFiles[path] {
+			cs.processEmptyFile(path)
+			delete(cs.writtenFiles, path)
+		}
+	}
+	cs.renderTemplates()
+}
+
+// groupChanges groups variables by configuration file they belong to, file values are returned separately, indexed by key
+func (cs *ConsulStorage) groupChanges(changes map[string]interface{}) (Configs, map[string]*p.FileValue) {
 	configs := make(Configs)
+	files := make(map[string]*p.FileValue)
 	for k, v := range changes {
+		if fileValue, ok := v.(*p.FileValue); ok {
+			files[k] = fileValue
+			continue
+		}
 		configPath := cs.generateConfigurationFilePath(k)
+		if configPath == "" {
+			continue
+		}
 		if _, ok := configs[configPath]; !ok {
 			configs[configPath] = make(ConfigContent)
 		}
 		configs[configPath][k] = v
 	}
-	for path, variables := range configs {
-		cs.writeToFile(path, variables)
+	return configs, files
+}
+
+// processEmptyFile handles file which no longer has any values, according to `consul.empty_files` setting
+func (cs *ConsulStorage) processEmptyFile(path string) {
+	absolutePath := cs.storage.AbsolutePath(path)
+	if !cs.storage.Exists(absolutePath) {
+		cs.releaseCandidate(path)
+		return
+	}
+
+	var err error
+	switch cs.config.Consul.EmptyFiles {
+	case consul.EmptyFilesRemove:
+		logger.Infof("consul:storage", "all values were removed from `%s`, removing file", path)
+		err = cs.storage.DeleteFile(absolutePath)
+	case consul.EmptyFilesArchive:
+		logger.Infof("consul:storage", "all values were removed from `%s`, archiving file", path)
+		err = cs.storage.MoveFile(absolutePath, fmt.Sprintf("%s.archived", absolutePath))
+	default:
+		logger.Infof("consul:storage", "all values were removed from `%s`, truncating file", path)
+		encode := formatEncoders[formatFromFilePath(path)]
+		cs.writeContent(path, encode(path, nil), cs.defaultPermissions(path))
+		return
 	}
+
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to process empty file (%s) - %s", path, err.Error())
+		logger.Error("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+		return
+	}
+	cs.releaseCandidate(path)
+	cs.fileWritten(path)
 }
 
-// writeToFile writes data to file
+// writeToFile writes data to file in the output format matching its extension
 func (cs *ConsulStorage) writeToFile(path string, variables ConfigContent) {
-	var fileLines []string
-
-	for key, value := range variables {
-		switch value.(type) {
-		case bool:
-			fileLines = append(fileLines, key+"="+fmt.Sprintf("%t", value))
-		case float32, float64:
-			fileLines = append(fileLines, key+"="+strconv.FormatFloat(value.(float64), 'f', -1, 64))
-		case int, int8, int16, int32, int64:
-			fileLines = append(fileLines, key+"="+fmt.Sprintf("%d", value))
-		case string:
-			formattedValue := fmt.Sprintf("%s", value)
-			fileLines = append(fileLines, key+"="+strconv.Quote(formattedValue))
+	var namedVariables []NamedVariable
+	for _, variable := range cs.nameVariables(path, variables) {
+		if unitValue, ok := variable.Value.(p.UnitValue); ok {
+			variable.Value = cs.convertUnitValue(path, cs.describeKey(variable.Key), unitValue)
 		}
+		namedVariables = append(namedVariables, variable)
 	}
 
-	tempFileHash, err := cs.writeToTempFile(path, fileLines)
+	encode := formatEncoders[formatFromFilePath(path)]
+	cs.writeContent(path, encode(path, namedVariables), cs.configurationPermissions(namedVariables))
+}
+
+// convertUnitValue converts value to the unit requested for the file, when conversion fails (e.g. `consul.units` rule
+// has unknown unit) value is written in its own unit and error is reported once
+func (cs *ConsulStorage) convertUnitValue(path, name string, value p.UnitValue) interface{} {
+	converted, err := value.Convert(cs.targetUnit(path, value))
 	if err == nil {
-		backupFilePath := fmt.Sprintf("%s.bak", path)
-		if cs.storage.Exists(cs.storage.AbsolutePath(path)) {
-			err = cs.storage.MoveFile(cs.storage.AbsolutePath(path), cs.storage.AbsolutePath(backupFilePath))
-			if err != nil {
-				errMsg := fmt.Sprintf("failed to create file backup (%s) - %s", path, err.Error())
-				logger.Errorf("consul:storage", errMsg)
-				cs.sendErrorNotification(errMsg)
+		return converted
+	}
+	errMsg := fmt.Sprintf("failed to convert `%s` for `%s`, writing it in its own unit - %s", name, path, err.Error())
+	if !cs.reportedUnits[errMsg] {
+		cs.reportedUnits[errMsg] = true
+		logger.Error("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+	}
+	converted, _ = value.Convert(value.TargetUnit())
+	return converted
+}
+
+// targetUnit returns unit value should be written in, unit set on the value itself takes precedence
+// over the first `consul.units` rule matching the file
+func (cs *ConsulStorage) targetUnit(path string, value p.UnitValue) string {
+	if unit := value.TargetUnit(); unit != "" {
+		return unit
+	}
+	for _, rule := range cs.config.Consul.Units {
+		if rule == nil {
+			continue
+		}
+		if matched, err := filepath.Match(rule.File, path); err != nil || !matched {
+			continue
+		}
+		switch value.(type) {
+		case *p.DurationValue:
+			if rule.Duration != "" {
+				return rule.Duration
+			}
+		case *p.SizeValue:
+			if rule.Size != "" {
+				return rule.Size
 			}
 		}
-		err = cs.storage.CreateDirectory(cs.storage.AbsolutePath(path))
-		if err != nil {
-			logger.Errorf("consul:storage", "failed to create data path")
-			cs.sendErrorNotification("failed to create data path")
-		} else {
-			err = cs.storage.MoveFile(cs.storage.AbsoluteTempPath(path), cs.storage.AbsolutePath(path))
-			if err != nil {
-				errMsg := fmt.Sprintf("failed to move `%s` from temporary folder to permanent location - %s", path, err.Error())
-				logger.Error("consul:storage", errMsg)
-				cs.sendErrorNotification(errMsg)
-
-				err = cs.storage.MoveFile(cs.storage.AbsolutePath(backupFilePath), cs.storage.AbsolutePath(path))
-				if err != nil {
-					errMsg := fmt.Sprintf("failed to restore file backup (%s) - %s", path, err.Error())
-					logger.Error("consul:storage", errMsg)
-					cs.sendErrorNotification(errMsg)
-				}
-			} else {
-				finalFileHash, err := cs.storage.ComputeFileHash(cs.storage.AbsolutePath(path))
-				if err != nil {
-					errMsg := fmt.Sprintf("failed to compute final file hash (%s) - %s", path, err.Error())
-					logger.Error("consul:storage", errMsg)
-					cs.sendErrorNotification(errMsg)
-				} else {
-					if tempFileHash != finalFileHash {
-						err = cs.storage.MoveFile(cs.storage.AbsolutePath(backupFilePath), cs.storage.AbsolutePath(path))
-						if err != nil {
-							errMsg := fmt.Sprintf("failed to restore file backup (%s) - %s", path, err.Error())
-							logger.Error("consul:storage", errMsg)
-							cs.sendErrorNotification(errMsg)
-						}
-					}
-				}
+	}
+	return ""
+}
+
+// NamedVariable describes variable with the name it will be written under
+type NamedVariable struct {
+	Name  string
+	Key   string
+	Value interface{}
+}
+
+// nameVariables converts keys to variable names sorted by name, when two keys produce the same
+// variable name in one file, only the first one (by Consul path) is kept and collision is reported
+func (cs *ConsulStorage) nameVariables(path string, variables ConfigContent) []NamedVariable {
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return cs.describeKey(keys[i]) < cs.describeKey(keys[j])
+	})
+
+	namedVariables := make([]NamedVariable, 0, len(keys))
+	usedNames := make(map[string]string)
+	for _, key := range keys {
+		name := cs.parser.VariableName(key)
+		if existingKey, exists := usedNames[name]; exists {
+			collision := fmt.Sprintf("`%s` and `%s` produce the same variable `%s` in `%s`, keeping `%s`", cs.describeKey(existingKey), cs.describeKey(key), name, path, cs.describeKey(existingKey))
+			if !cs.reportedCollisions[collision] {
+				cs.reportedCollisions[collision] = true
+				logger.Error("consul:storage", collision)
+				cs.sendErrorNotification(collision)
 			}
+			continue
 		}
+		usedNames[name] = key
+		namedVariables = append(namedVariables, NamedVariable{
+			Name:  name,
+			Key:   key,
+			Value: variables[key],
+		})
+	}
+
+	sort.Slice(namedVariables, func(i, j int) bool {
+		return namedVariables[i].Name < namedVariables[j].Name
+	})
+	return namedVariables
+}
+
+// describeKey returns Consul path for given key (or key itself if path is not known)
+func (cs *ConsulStorage) describeKey(key string) string {
+	path, err := cs.parser.GetReferenceStorage().Get(key)
+	if err != nil {
+		return key
 	}
+	return path
 }
 
-// sendErrorNotification sends error notification
-func (cs *ConsulStorage) sendErrorNotification(message string) {
-	tpl, err := template.New("").Parse(`
-*Message:*     {{ .Message }}
-*Application:* {{ .Application }}
-*Version:*     {{ .Version }}-{{ .ShaHash }}
-*Server:*      {{ .Address }}
-`)
+// writeFileValue writes contents of file value to its target path
+func (cs *ConsulStorage) writeFileValue(file *p.FileValue) {
+	cs.writeContent(file.Path, file.Content, cs.fileValuePermissions(file))
+}
+
+// SetWriteHandler sets function which is called with path of every file which was written or removed
+func (cs *ConsulStorage) SetWriteHandler(handler func(path string)) {
+	cs.Lock()
+	defer cs.Unlock()
+	cs.writeHandler = handler
+}
+
+// fileWritten passes path of written (or removed) file to write handler
+func (cs *ConsulStorage) fileWritten(path string) {
+	if cs.writeHandler != nil {
+		cs.writeHandler(path)
+	}
+}
+
+// SetCandidateValidator sets validator which checks candidate version of every file before it is moved into place
+func (cs *ConsulStorage) SetCandidateValidator(validator CandidateValidator) {
+	cs.Lock()
+	defer cs.Unlock()
+	cs.candidateValidator = validator
+}
+
+// validateCandidate checks temporary file with candidate validator, rejected file is removed and previous version is kept
+func (cs *ConsulStorage) validateCandidate(path string) error {
+	if cs.candidateValidator == nil {
+		return nil
+	}
+	fileStorage := cs.storageFor(path)
+	err := cs.candidateValidator.Validate(path, fileStorage.AbsoluteTempPath(path))
 	if err == nil {
-		templateValues := struct {
-			Message     string
-			Application string
-			Version     string
-			ShaHash     string
-			Address     string
-		}{
-			Message:     message,
-			Application: "Consul Config Manager",
-			Version:     cs.config.Application.Version,
-			ShaHash:     cs.config.Application.CommitSha,
-			Address:     cs.config.Agent.Address(),
-		}
+		delete(cs.reportedRejections, path)
+		return nil
+	}
+	fileStorage.DeleteFile(fileStorage.AbsoluteTempPath(path))
+
+	errMsg := fmt.Sprintf("`%s` was rejected by validator, keeping previous version - %s", path, err.Error())
+	if cs.reportedRejections[path] != errMsg {
+		cs.reportedRejections[path] = errMsg
+		logger.Error("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+	}
+	return err
+}
+
+// releaseCandidate removes file which no longer exists from the list of held back files
+func (cs *ConsulStorage) releaseCandidate(path string) {
+	delete(cs.reportedRejections, path)
+	if cs.candidateValidator != nil {
+		cs.candidateValidator.Release(path)
+	}
+}
 
-		var templateBuffer bytes.Buffer
-		err = tpl.Execute(&templateBuffer, templateValues)
-		if err == nil {
-			cs.config.Notifier.DeliverNotification(notifier.TELEGRAM, notifierPackage.NewNotification(notifierPackage.NotificationOptions{
-				Type:    notifierPackage.Error,
-				Title:   fmt.Sprintf("%s - consul:storage error", cs.config.Agent.Network.Hostname()),
-				Message: templateBuffer.String(),
-			}))
+// storageFor returns storage which should be used to write file, absolute paths are written as is,
+// other paths are relative to `consul.write_to` directory
+func (cs *ConsulStorage) storageFor(path string) *s.Storage {
+	if filepath.IsAbs(path) {
+		return cs.rootStorage
+	}
+	return cs.storage
+}
+
+// writeContent writes contents to temporary file (with requested mode and ownership) and moves it to permanent location
+// (keeping backup of previous version), returned error is already logged and reported
+func (cs *ConsulStorage) writeContent(path string, content []byte, permissions *filePermissions) error {
+	fileStorage := cs.storageFor(path)
+	cs.reportPermissionsDrift(path)
+	tempFileHash, err := cs.writeToTempFile(path, content, permissions)
+	if err != nil {
+		return err
+	}
+	// Every update rewrites all files, file is only replaced (and write handler called) when it actually changed
+	if cs.isFileUnchanged(path, tempFileHash) {
+		fileStorage.DeleteFile(fileStorage.AbsoluteTempPath(path))
+		cs.releaseCandidate(path)
+		cs.recordPermissions(path)
+		return nil
+	}
+	if err = cs.validateCandidate(path); err != nil {
+		return err
+	}
+	backupFilePath := fmt.Sprintf("%s.bak", path)
+	if fileStorage.Exists(fileStorage.AbsolutePath(path)) {
+		err = fileStorage.MoveFile(fileStorage.AbsolutePath(path), fileStorage.AbsolutePath(backupFilePath))
+		if err != nil {
+			errMsg := fmt.Sprintf("failed to create file backup (%s) - %s", path, err.Error())
+			logger.Errorf("consul:storage", errMsg)
+			cs.sendErrorNotification(errMsg)
 		}
 	}
+	err = fileStorage.CreateDirectory(fileStorage.AbsolutePath(path))
+	if err != nil {
+		logger.Errorf("consul:storage", "failed to create data path")
+		cs.sendErrorNotification("failed to create data path")
+		return err
+	}
+	cs.applyDirectoryPermissions(path, permissions)
+	err = fileStorage.MoveFile(fileStorage.AbsoluteTempPath(path), fileStorage.AbsolutePath(path))
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to move `%s` from temporary folder to permanent location - %s", path, err.Error())
+		logger.Error("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+		cs.restoreBackup(path, backupFilePath)
+		return err
+	}
+	finalFileHash, err := fileStorage.ComputeFileHash(fileStorage.AbsolutePath(path))
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to compute final file hash (%s) - %s", path, err.Error())
+		logger.Error("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+		return err
+	}
+	if tempFileHash != finalFileHash {
+		cs.restoreBackup(path, backupFilePath)
+		return fmt.Errorf("hash of `%s` does not match hash of temporary file", path)
+	}
+	cs.recordPermissions(path)
+	cs.fileWritten(path)
+	return nil
 }
 
-// writeToTempFile writes data to temporary file
-func (cs *ConsulStorage) writeToTempFile(path string, fileLines []string) (string, error) {
-	absolutePath := cs.storage.AbsoluteTempPath(path)
-	err := cs.storage.CreateFile(absolutePath)
+// isFileUnchanged reports whether file already has the same content, mode and ownership as the temporary file
+func (cs *ConsulStorage) isFileUnchanged(path, tempFileHash string) bool {
+	fileStorage := cs.storageFor(path)
+	info, err := os.Stat(fileStorage.AbsolutePath(path))
 	if err != nil {
+		return false
+	}
+	tempInfo, err := os.Stat(fileStorage.AbsoluteTempPath(path))
+	if err != nil || info.Mode().Perm() != tempInfo.Mode().Perm() {
+		return false
+	}
+	uid, gid, ok := fileOwnership(info)
+	tempUID, tempGID, tempOk := fileOwnership(tempInfo)
+	if ok && tempOk && (uid != tempUID || gid != tempGID) {
+		return false
+	}
+	hash, err := fileStorage.ComputeFileHash(fileStorage.AbsolutePath(path))
+	return err == nil && hash == tempFileHash
+}
+
+// restoreBackup moves backup of the file back to its permanent location
+func (cs *ConsulStorage) restoreBackup(path, backupFilePath string) {
+	fileStorage := cs.storageFor(path)
+	if err := fileStorage.MoveFile(fileStorage.AbsolutePath(backupFilePath), fileStorage.AbsolutePath(path)); err != nil {
+		errMsg := fmt.Sprintf("failed to restore file backup (%s) - %s", path, err.Error())
+		logger.Error("consul:storage", errMsg)
+		cs.sendErrorNotification(errMsg)
+	}
+}
+
+// sendErrorNotification sends error notification
+func (cs *ConsulStorage) sendErrorNotification(message string) {
+	cs.config.SendNotification(notifierPackage.Error, "consul:storage error", message)
+}
+
+// writeToTempFile writes data to temporary file
+func (cs *ConsulStorage) writeToTempFile(path string, content []byte, permissions *filePermissions) (string, error) {
+	fileStorage := cs.storageFor(path)
+	absolutePath := fileStorage.AbsoluteTempPath(path)
+	if err := fileStorage.CreateDirectory(absolutePath); err != nil {
+		logger.Errorf("consul:storage", "failed to create temporary directory - %s", err.Error())
+		return "", err
+	}
+	// File left by interrupted write keeps its old mode, so it is removed and the file is created exclusively,
+	// with requested mode and ownership applied before any content is written
+	if err := os.Remove(absolutePath); err != nil && !os.IsNotExist(err) {
+		logger.Errorf("consul:storage", "failed to remove stale temporary file - %s", err.Error())
 		return "", err
 	}
-	file, err := os.OpenFile(absolutePath, os.O_RDWR|os.O_TRUNC, 0755)
+	file, err := os.OpenFile(absolutePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
 	if err != nil {
 		logger.Errorf("consul:storage", "failed to write configuration to file - %s", err)
 		return "", err
 	}
 
-	dataWriter := bufio.NewWriter(file)
+	if err = file.Chmod(permissions.Mode); err != nil {
+		logger.Errorf("consul:storage", "failed to change file mode - %s", err.Error())
+		file.Close()
+		return "", err
+	}
 
-	for _, line := range fileLines {
-		_, err := dataWriter.WriteString(line + "\n")
-		if err != nil {
-			logger.Warnf("consul:storage", "failed to write line to file - %s", err)
-		}
+	if err = applyOwnership(absolutePath, permissions.Owner, permissions.Group); err != nil {
+		logger.Errorf("consul:storage", "failed to change file owner - %s", err.Error())
+		file.Close()
+		return "", err
 	}
 
-	if err = dataWriter.Flush(); err != nil {
-		logger.Warnf("consul:storage", "failed to flush data writer - %s", err)
+	if _, err = file.Write(content); err != nil {
+		logger.Warnf("consul:storage", "failed to write data to file - %s", err)
+		file.Close()
 		return "", err
 	}
 
@@ -194,7 +557,7 @@ func (cs *ConsulStorage) writeToTempFile(path string, fileLines []string) (strin
 		return "", err
 	}
 
-	hash, err := cs.storage.ComputeFileHash(absolutePath)
+	hash, err := fileStorage.ComputeFileHash(absolutePath)
 	if err != nil {
 		logger.Errorf("consul:storage", "failed to compute hash for a file - %s", err.Error())
 		return "", err
@@ -202,12 +565,39 @@ func (cs *ConsulStorage) writeToTempFile(path string, fileLines []string) (strin
 	return hash, nil
 }
 
-// generateConfigurationFilePath generates OS independent path to configuration file
+// ConfigurationFilePath returns path (relative to `consul.write_to`) of configuration file given key is written to,
+// empty path is returned for keys which are not written to any file
+func (cs *ConsulStorage) ConfigurationFilePath(key string) string {
+	// Mapping and format errors are recorded in maps which are also written while changes are processed
+	cs.Lock()
+	defer cs.Unlock()
+	return cs.generateConfigurationFilePath(key)
+}
+
+// generateConfigurationFilePath generates OS independent path to configuration file according to `consul.mapping` rules,
+// empty path is returned for keys which should not be written to any file
 func (cs *ConsulStorage) generateConfigurationFilePath(key string) string {
-	path, err := cs.parser.GetReferenceStorage().Get(key)
+	path, err := cs.parser.GetReferenceStorage().GetSource(key)
+	if err != nil {
+		cs.reportMappingError(fmt.Sprintf("failed to retrieve Consul path of `%s`, key is not written", key))
+		return ""
+	}
+	path = strings.Trim(path, "/")
+	filePath, err := cs.mapKeyToFile(path)
 	if err != nil {
-		logger.Fatalf("provider:consul:storage", "failed to retrieve key reference to path")
+		cs.reportMappingError(fmt.Sprintf("`%s` is not written - %s", path, err.Error()))
+		return ""
+	}
+	if filePath == "" {
+		return ""
+	}
+	return filePath + formatExtensions[cs.fileFormat(path)]
+}
+
+// joinLines joins lines into file contents, terminating every line with new line character
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
 	}
-	stringParts := strings.SplitN(path, "/", -1)
-	return fmt.Sprintf("%s%s%s.env", strings.ToLower(stringParts[0]), string(os.PathSeparator), strings.ToLower(stringParts[1]))
+	return strings.Join(lines, "\n") + "\n"
 }
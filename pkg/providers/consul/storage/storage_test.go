@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileValueIsWrittenVerbatim(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	process(consulStorage, parser, pair("app/certificate", `{"type":"file","path":"certs/tls.pem","value":"line 1\nline 2","mode":"0600"}`))
+
+	if content := readFile(t, consulStorage, "certs/tls.pem"); content != "line 1\nline 2" {
+		t.Fatalf("unexpected content %q", content)
+	}
+	info, err := os.Stat(filepath.Join(consulStorage.config.Consul.WriteTo, "certs", "tls.pem"))
+	if err != nil {
+		t.Fatalf("failed to stat file - %s", err.Error())
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected mode 0600, got %s", info.Mode().Perm())
+	}
+}
+
+func TestFileValueMovedToAnotherPath(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.config.Consul.EmptyFiles = "remove"
+	process(consulStorage, parser, pair("app/certificate", `{"type":"file","path":"certs/old.pem","value":"secret"}`))
+	process(consulStorage, parser, pair("app/certificate", `{"type":"file","path":"certs/new.pem","value":"secret"}`))
+
+	if content := readFile(t, consulStorage, "certs/new.pem"); content != "secret" {
+		t.Fatalf("unexpected content %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(consulStorage.config.Consul.WriteTo, "certs", "old.pem")); !os.IsNotExist(err) {
+		t.Fatalf("expected file at previous path to be removed")
+	}
+}
+
+func TestDeletedKeyIsRemovedFromFile(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	process(consulStorage, parser,
+		pair("app/db/host", `{"type":"string","value":"h"}`),
+		pair("app/db/port", `{"type":"number","value":5432}`),
+	)
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+
+	content := readFile(t, consulStorage, "app/db.env")
+	if strings.Contains(content, "CONSUL_APP_DB_PORT") || !strings.Contains(content, `CONSUL_APP_DB_HOST="h"`) {
+		t.Fatalf("expected deleted key to be removed from file, got:\n%s", content)
+	}
+}
+
+func TestFileWithoutValuesIsProcessedAccordingToEmptyFiles(t *testing.T) {
+	tests := map[string]func(t *testing.T, path string){
+		"truncate": func(t *testing.T, path string) {
+			content, err := ioutil.ReadFile(path)
+			if err != nil || len(content) != 0 {
+				t.Fatalf("expected file to be truncated, got %q (%v)", content, err)
+			}
+		},
+		"remove": func(t *testing.T, path string) {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Fatalf("expected file to be removed")
+			}
+		},
+		"archive": func(t *testing.T, path string) {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Fatalf("expected file to be moved")
+			}
+			content, err := ioutil.ReadFile(path + ".archived")
+			if err != nil || !strings.Contains(string(content), "CONSUL_APP_DB_HOST") {
+				t.Fatalf("expected previous contents to be archived, got %q (%v)", content, err)
+			}
+		},
+	}
+	for mode, check := range tests {
+		t.Run(mode, func(t *testing.T) {
+			consulStorage, parser := newTestStorage(t)
+			consulStorage.config.Consul.EmptyFiles = mode
+			process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"h"}`))
+			process(consulStorage, parser)
+			check(t, filepath.Join(consulStorage.config.Consul.WriteTo, "app", "db.env"))
+		})
+	}
+}
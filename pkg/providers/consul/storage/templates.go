@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	consulAPI "github.com/hashicorp/consul/api"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+	p "github.com/leads-su/consul-config-manager/pkg/providers/consul/parser"
+	"github.com/leads-su/logger"
+)
+
+const (
+	// templateInputValue is recorded when template reads value (or checks its presence)
+	templateInputValue = "value"
+	// templateInputTree is recorded when template reads all values under prefix
+	templateInputTree = "tree"
+	// templateInputReference is recorded when template reads target of the reference
+	templateInputReference = "reference"
+	// templateInputService is recorded when template reads instances of the service
+	templateInputService = "service"
+)
+
+// ServiceCatalog provides healthy instances of services registered in Consul, it is implemented by `*api.Health`
+type ServiceCatalog interface {
+	Service(service, tag string, passingOnly bool, q *consulAPI.QueryOptions) ([]*consulAPI.ServiceEntry, *consulAPI.QueryMeta, error)
+}
+
+// TemplateService describes healthy instance of the service, as it is seen by templates
+type TemplateService struct {
+	ID      string
+	Name    string
+	Address string
+	Port    int
+	Tags    []string
+	Meta    map[string]string
+}
+
+// templateInput describes data template has read during its last render
+type templateInput struct {
+	Kind      string
+	Arguments []string
+	Hash      string
+}
+
+// renderedTemplate describes the last successful render of the template
+type renderedTemplate struct {
+	SourceHash string
+	Inputs     []*templateInput
+}
+
+// templateRenderer renders single template, keeping track of the data it reads
+type templateRenderer struct {
+	storage     *ConsulStorage
+	destination string
+	inputs      map[string]*templateInput
+}
+
+// SetServiceCatalog sets catalog used by `service` template function
+func (cs *ConsulStorage) SetServiceCatalog(catalog ServiceCatalog) {
+	cs.Lock()
+	defer cs.Unlock()
+	cs.serviceCatalog = catalog
+}
+
+// renderTemplates renders every configured template which source or inputs changed since its last render
+func (cs *ConsulStorage) renderTemplates() {
+	for _, tpl := range cs.config.Consul.Templates {
+		if tpl == nil || tpl.Source == "" || tpl.Destination == "" {
+			continue
+		}
+		cs.renderTemplate(tpl)
+	}
+	cs.pruneServiceWatches()
+}
+
+// renderTemplate renders template to its destination, previous version of the file is kept when render fails
+func (cs *ConsulStorage) renderTemplate(tpl *consul.Template) {
+	source, err := ioutil.ReadFile(tpl.Source)
+	if err != nil {
+		cs.reportTemplateError(tpl, fmt.Errorf("failed to read template - %s", err.Error()))
+		return
+	}
+	sourceHash := hashTemplateData(string(source))
+
+	fileStorage := cs.storageFor(tpl.Destination)
+	previous, rendered := cs.renderedTemplates[tpl.Destination]
+	if rendered && previous.SourceHash == sourceHash && fileStorage.Exists(fileStorage.AbsolutePath(tpl.Destination)) && !cs.templateInputsChanged(tpl.Destination, previous.Inputs) {
+		return
+	}
+
+	mode := os.FileMode(0644)
+	if tpl.Perms != "" {
+		parsedMode, err := strconv.ParseUint(tpl.Perms, 8, 32)
+		if err != nil {
+			cs.reportTemplateError(tpl, fmt.Errorf("invalid file mode `%s` - %s", tpl.Perms, err.Error()))
+			return
+		}
+		mode = os.FileMode(parsedMode)
+	}
+
+	renderer := &templateRenderer{
+		storage:     cs,
+		destination: tpl.Destination,
+		inputs:      make(map[string]*templateInput),
+	}
+	parsedTemplate, err := template.New(filepath.Base(tpl.Source)).Funcs(renderer.functions()).Parse(string(source))
+	if err != nil {
+		cs.reportTemplateError(tpl, err)
+		return
+	}
+	var buffer bytes.Buffer
+	if err = parsedTemplate.Execute(&buffer, nil); err != nil {
+		// Template is rendered again as soon as instances of the service it uses are loaded
+		if errors.Is(err, errServicesPending) {
+			return
+		}
+		cs.reportTemplateError(tpl, err)
+		return
+	}
+
+	logger.Infof("consul:storage", "rendering template `%s` to `%s`", tpl.Source, tpl.Destination)
+	permissions := &filePermissions{
+		Mode:  mode,
+		Owner: tpl.Owner,
+		Group: tpl.Group,
+	}
+	if err = cs.writeContent(tpl.Destination, buffer.Bytes(), permissions); err != nil {
+		return
+	}
+	delete(cs.reportedTemplateErrors, tpl.Destination)
+	cs.renderedTemplates[tpl.Destination] = &renderedTemplate{
+		SourceHash: sourceHash,
+		Inputs:     renderer.sortedInputs(),
+	}
+}
+
+// reportTemplateError logs and reports error of the template, the same error is only reported once
+func (cs *ConsulStorage) reportTemplateError(tpl *consul.Template, err error) {
+	errMsg := fmt.Sprintf("failed to render template `%s` to `%s`, keeping previous version - %s", tpl.Source, tpl.Destination, err.Error())
+	if cs.reportedTemplateErrors[tpl.Destination] == errMsg {
+		return
+	}
+	cs.reportedTemplateErrors[tpl.Destination] = errMsg
+	logger.Error("consul:storage", errMsg)
+	cs.sendErrorNotification(errMsg)
+}
+
+// templateInputsChanged reports whether any data template has read during its last render is now different
+func (cs *ConsulStorage) templateInputsChanged(destination string, inputs []*templateInput) bool {
+	for _, input := range inputs {
+		var data interface{}
+		switch input.Kind {
+		case templateInputValue:
+			value, found := cs.lookupTemplateValue(destination, input.Arguments[0])
+			data = []interface{}{found, value}
+		case templateInputTree:
+			data = cs.lookupTemplateTree(destination, input.Arguments[0])
+		case templateInputReference:
+			data = cs.lookupTemplateReference(input.Arguments[0])
+		case templateInputService:
+			services, err := cs.lookupTemplateService(input.Arguments[0], input.Arguments[1])
+			if err != nil {
+				return true
+			}
+			data = services
+		}
+		if hashTemplateData(data) != input.Hash {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTemplateValue returns published value of the key under given Consul path, converted the way it is written to files
+func (cs *ConsulStorage) lookupTemplateValue(destination, path string) (interface{}, bool) {
+	key, err := cs.parser.GetReferenceStorage().Get(path)
+	if err != nil {
+		return nil, false
+	}
+	value, found := cs.parser.LiveData()[key]
+	if !found {
+		return nil, false
+	}
+	return cs.templateValue(destination, path, value), true
+}
+
+// lookupTemplateTree returns published values of all keys under given Consul path prefix, indexed by path relative to the prefix
+func (cs *ConsulStorage) lookupTemplateTree(destination, prefix string) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for key, value := range cs.parser.LiveData() {
+		path, err := cs.parser.GetReferenceStorage().Get(key)
+		if err != nil || (prefix != "" && path != prefix && !strings.HasPrefix(path, prefix+"/")) {
+			continue
+		}
+		tree[strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")] = cs.templateValue(destination, path, value)
+	}
+	return tree
+}
+
+// lookupTemplateReference returns Consul path of the final target of the reference, empty string is returned for other keys
+func (cs *ConsulStorage) lookupTemplateReference(path string) string {
+	provenance, found := cs.parser.Explain(path)
+	if !found || len(provenance.ReferenceChain) == 0 {
+		return ""
+	}
+	return provenance.ReferenceChain[len(provenance.ReferenceChain)-1]
+}
+
+// lookupTemplateService returns healthy instances of the service, optionally filtered by tag, from the cache kept
+// up to date by blocking queries, so Consul is never queried while configuration is being written
+func (cs *ConsulStorage) lookupTemplateService(name, tag string) ([]*TemplateService, error) {
+	return cs.cachedServices(name, tag)
+}
+
+// templateValue converts value the way it is written to files, file values are returned as their contents
+func (cs *ConsulStorage) templateValue(destination, path string, value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case *p.FileValue:
+		return string(typedValue.Content)
+	case p.UnitValue:
+		return cs.convertUnitValue(destination, path, typedValue)
+	}
+	return value
+}
+
+// functions returns functions available in templates
+func (renderer *templateRenderer) functions() template.FuncMap {
+	cs := renderer.storage
+	return template.FuncMap{
+		"value": func(path string) (interface{}, error) {
+			path = strings.Trim(path, "/")
+			value, found := cs.lookupTemplateValue(renderer.destination, path)
+			renderer.record(templateInputValue, []interface{}{found, value}, path)
+			if !found {
+				return nil, fmt.Errorf("key `%s` is not present", path)
+			}
+			return value, nil
+		},
+		"valueOr": func(path string, fallback interface{}) interface{} {
+			path = strings.Trim(path, "/")
+			value, found := cs.lookupTemplateValue(renderer.destination, path)
+			renderer.record(templateInputValue, []interface{}{found, value}, path)
+			if !found {
+				return fallback
+			}
+			return value
+		},
+		"exists": func(path string) bool {
+			path = strings.Trim(path, "/")
+			value, found := cs.lookupTemplateValue(renderer.destination, path)
+			renderer.record(templateInputValue, []interface{}{found, value}, path)
+			return found
+		},
+		"tree": func(prefix string) map[string]interface{} {
+			prefix = strings.Trim(prefix, "/")
+			tree := cs.lookupTemplateTree(renderer.destination, prefix)
+			renderer.record(templateInputTree, tree, prefix)
+			return tree
+		},
+		"reference": func(path string) string {
+			path = strings.Trim(path, "/")
+			target := cs.lookupTemplateReference(path)
+			renderer.record(templateInputReference, target, path)
+			return target
+		},
+		"service": func(name string, tags ...string) ([]*TemplateService, error) {
+			if len(tags) > 1 {
+				return nil, fmt.Errorf("only one tag can be used to filter instances of `%s`", name)
+			}
+			tag := ""
+			if len(tags) == 1 {
+				tag = tags[0]
+			}
+			services, err := cs.lookupTemplateService(name, tag)
+			if err != nil {
+				return nil, err
+			}
+			renderer.record(templateInputService, services, name, tag)
+			return services, nil
+		},
+		"join": func(separator string, value interface{}) string {
+			if items, ok := value.([]string); ok {
+				return strings.Join(items, separator)
+			}
+			if text, ok := joinValue(value, separator); ok {
+				return text
+			}
+			return fmt.Sprintf("%v", value)
+		},
+		"toJSON": func(value interface{}) string {
+			return string(bytes.TrimSpace(marshalJSON(value, "")))
+		},
+	}
+}
+
+// record stores hash of the data template has read, so the change of this data triggers new render
+func (renderer *templateRenderer) record(kind string, data interface{}, arguments ...string) {
+	renderer.inputs[kind+"\x00"+strings.Join(arguments, "\x00")] = &templateInput{
+		Kind:      kind,
+		Arguments: arguments,
+		Hash:      hashTemplateData(data),
+	}
+}
+
+// sortedInputs returns inputs recorded during render, sorted so they are always checked in the same order
+func (renderer *templateRenderer) sortedInputs() []*templateInput {
+	identifiers := make([]string, 0, len(renderer.inputs))
+	for identifier := range renderer.inputs {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+	inputs := make([]*templateInput, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		inputs = append(inputs, renderer.inputs[identifier])
+	}
+	return inputs
+}
+
+// hashTemplateData computes hash of the data template has read
+func hashTemplateData(data interface{}) string {
+	content, err := json.Marshal(data)
+	if err != nil {
+		content = []byte(fmt.Sprintf("%#v", data))
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
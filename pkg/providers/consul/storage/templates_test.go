@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	consulAPI "github.com/hashicorp/consul/api"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+// addTemplate writes template source to temporary directory and configures it to be rendered to destination
+func addTemplate(t *testing.T, consulStorage *ConsulStorage, source, destination string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "template.tpl")
+	writeTemplate(t, path, source)
+	consulStorage.config.Consul.Templates = append(consulStorage.config.Consul.Templates, &consul.Template{
+		Source:      path,
+		Destination: destination,
+	})
+	return path
+}
+
+// writeTemplate replaces contents of the template source
+func writeTemplate(t *testing.T, path, source string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write template - %s", err.Error())
+	}
+}
+
+func TestTemplateIsRenderedWithValues(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	addTemplate(t, consulStorage, `host={{ value "app/db/host" }}
+port={{ value "/app/db/port/" }}
+user={{ valueOr "app/db/user" "postgres" }}
+{{ if exists "app/db/ssl" }}ssl=on{{ else }}ssl=off{{ end }}
+{{ range $path, $value := tree "app/db" }}{{ $path }};{{ end }}
+{{ reference "app/db/replica" }}`, "rendered/db.conf")
+	process(consulStorage, parser,
+		pair("app/db/host", `{"type":"string","value":"db.internal"}`),
+		pair("app/db/port", `{"type":"number","value":5432}`),
+		pair("app/db/replica", `{"type":"reference","value":"app/db/host"}`),
+	)
+
+	expected := "host=db.internal\nport=5432\nuser=postgres\nssl=off\nhost;port;replica;\napp/db/host"
+	if content := readFile(t, consulStorage, "rendered/db.conf"); content != expected {
+		t.Fatalf("unexpected rendered template:\n%s", content)
+	}
+}
+
+func TestTemplateIsRenderedAgainOnlyWhenItsInputsChange(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	addTemplate(t, consulStorage, `host={{ value "app/db/host" }}`, "rendered/db.conf")
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"a"}`))
+
+	destination := filepath.Join(consulStorage.config.Consul.WriteTo, "rendered/db.conf")
+	if err := ioutil.WriteFile(destination, []byte("edited"), 0644); err != nil {
+		t.Fatalf("failed to edit rendered template - %s", err.Error())
+	}
+	process(consulStorage, parser,
+		pair("app/db/host", `{"type":"string","value":"a"}`),
+		pair("app/db/port", `{"type":"number","value":5432}`),
+	)
+	if content := readFile(t, consulStorage, "rendered/db.conf"); content != "edited" {
+		t.Fatalf("expected template not to be rendered when its inputs did not change, got:\n%s", content)
+	}
+
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"b"}`))
+	if content := readFile(t, consulStorage, "rendered/db.conf"); content != "host=b" {
+		t.Fatalf("expected template to be rendered after input change, got:\n%s", content)
+	}
+}
+
+func TestFailedTemplateKeepsPreviousVersion(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	source := addTemplate(t, consulStorage, `host={{ value "app/db/host" }}`, "rendered/db.conf")
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"a"}`))
+
+	writeTemplate(t, source, `host={{ value "app/db/missing" }}`)
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"b"}`))
+	if content := readFile(t, consulStorage, "rendered/db.conf"); content != "host=a" {
+		t.Fatalf("expected previous version to be kept, got:\n%s", content)
+	}
+
+	writeTemplate(t, source, `host={{ value "app/db/host"`)
+	process(consulStorage, parser, pair("app/db/host", `{"type":"string","value":"c"}`))
+	if content := readFile(t, consulStorage, "rendered/db.conf"); content != "host=a" {
+		t.Fatalf("expected previous version to be kept, got:\n%s", content)
+	}
+}
+
+// staticCatalog is a service catalog which returns the same instances once and then blocks until query is cancelled
+type staticCatalog struct {
+	entries []*consulAPI.ServiceEntry
+}
+
+func (catalog *staticCatalog) Service(service, tag string, passingOnly bool, q *consulAPI.QueryOptions) ([]*consulAPI.ServiceEntry, *consulAPI.QueryMeta, error) {
+	if q.WaitIndex == 0 {
+		return catalog.entries, &consulAPI.QueryMeta{LastIndex: 1}, nil
+	}
+	<-q.Context().Done()
+	return nil, nil, context.Canceled
+}
+
+func TestTemplateIsRenderedWithServiceInstances(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.SetServiceCatalog(&staticCatalog{entries: []*consulAPI.ServiceEntry{
+		{Service: &consulAPI.AgentService{ID: "db-1", Service: "db", Address: "10.0.0.1", Port: 5432}},
+	}})
+	defer consulStorage.StopServiceWatches()
+	addTemplate(t, consulStorage, `{{ range service "db" }}{{ .Address }}:{{ .Port }}{{ end }}`, "rendered/db.conf")
+	process(consulStorage, parser)
+
+	select {
+	case <-consulStorage.ServicesChanged():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("instances of the service were not loaded")
+	}
+	consulStorage.RenderTemplates()
+	if content := readFile(t, consulStorage, "rendered/db.conf"); content != "10.0.0.1:5432" {
+		t.Fatalf("unexpected rendered template:\n%s", content)
+	}
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+func TestUnitValuesAreWrittenInUnitsOfMatchingRule(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.config.Consul.Units = []*consul.UnitRule{
+		{File: "web/*", Duration: "s"},
+		{File: "app/*", Duration: "ms", Size: "MiB"},
+	}
+	process(consulStorage, parser,
+		pair("app/db/timeout", `{"type":"duration","value":"1m30s"}`),
+		pair("app/db/pool", `{"type":"size","value":"64MiB"}`),
+		pair("app/db/ttl", `{"type":"duration","value":"2h","unit":"h"}`),
+		pair("worker/db/timeout", `{"type":"duration","value":"1m30s"}`),
+	)
+
+	content := readFile(t, consulStorage, "app/db.env")
+	for _, line := range []string{"CONSUL_APP_DB_TIMEOUT=90000", "CONSUL_APP_DB_POOL=64", "CONSUL_APP_DB_TTL=2"} {
+		if !strings.Contains(content, line) {
+			t.Fatalf("expected `%s` to be written, got:\n%s", line, content)
+		}
+	}
+	if content := readFile(t, consulStorage, "worker/db.env"); !strings.Contains(content, `CONSUL_WORKER_DB_TIMEOUT="1m30s"`) {
+		t.Fatalf("expected duration string without matching rule, got:\n%s", content)
+	}
+}
+
+func TestUnitValueWithUnknownRuleUnitIsWrittenInItsOwnUnit(t *testing.T) {
+	consulStorage, parser := newTestStorage(t)
+	consulStorage.config.Consul.Units = []*consul.UnitRule{{File: "app/*", Size: "parsecs"}}
+	process(consulStorage, parser, pair("app/db/pool", `{"type":"size","value":"1KiB"}`))
+
+	if content := readFile(t, consulStorage, "app/db.env"); !strings.Contains(content, "CONSUL_APP_DB_POOL=1024") {
+		t.Fatalf("expected size to be written in bytes, got:\n%s", content)
+	}
+}
@@ -0,0 +1,146 @@
+package validator
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+	"github.com/leads-su/consul-config-manager/pkg/utils"
+)
+
+const (
+	// filePlaceholder is replaced with path of the candidate file in command and arguments
+	filePlaceholder = "{{file}}"
+	// maxOutputLength limits validator output kept for held back files
+	maxOutputLength = 4096
+)
+
+// HeldBackFile describes file which candidate version was rejected by validator, previous version is kept in place
+type HeldBackFile struct {
+	Path       string    `json:"path"`
+	Validator  string    `json:"validator"`
+	ExitCode   int       `json:"exit_code"`
+	Output     string    `json:"output"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+// Validator runs commands configured in `consul.validators` against candidate versions of files
+// and keeps track of files which are held back
+type Validator struct {
+	sync.RWMutex
+	// config is an instance of application configuration
+	config *cfg.Config
+
+	// heldBack is a list of files which are currently held back, indexed by file path
+	heldBack map[string]*HeldBackFile
+}
+
+// NewValidator creates new instance of configuration file validator
+func NewValidator(config *cfg.Config) *Validator {
+	return &Validator{
+		config:   config,
+		heldBack: make(map[string]*HeldBackFile),
+	}
+}
+
+// Validate runs every validator matching the file against candidate file, file is held back until all of them succeed
+func (validator *Validator) Validate(path string, candidatePath string) error {
+	for _, options := range validator.config.Consul.Validators {
+		if options == nil || !utils.MatchesFilePattern(options.File, path) {
+			continue
+		}
+		exitCode, output, err := runValidator(options, candidatePath)
+		if err == nil {
+			continue
+		}
+
+		validator.Lock()
+		heldBack, exists := validator.heldBack[path]
+		if !exists || heldBack.Output != output || heldBack.ExitCode != exitCode {
+			validator.heldBack[path] = &HeldBackFile{
+				Path:       path,
+				Validator:  describeValidator(options),
+				ExitCode:   exitCode,
+				Output:     output,
+				RejectedAt: time.Now(),
+			}
+		}
+		validator.Unlock()
+
+		if output != "" {
+			return fmt.Errorf("`%s` failed (%s): %s", describeValidator(options), err.Error(), output)
+		}
+		return fmt.Errorf("`%s` failed (%s)", describeValidator(options), err.Error())
+	}
+
+	validator.Release(path)
+	return nil
+}
+
+// Release removes file from the list of held back files
+func (validator *Validator) Release(path string) {
+	validator.Lock()
+	defer validator.Unlock()
+	delete(validator.heldBack, path)
+}
+
+// HeldBackFiles returns list of files which are currently held back, sorted by path
+func (validator *Validator) HeldBackFiles() []*HeldBackFile {
+	validator.RLock()
+	defer validator.RUnlock()
+	files := make([]*HeldBackFile, 0, len(validator.heldBack))
+	for _, heldBack := range validator.heldBack {
+		files = append(files, heldBack)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// runValidator executes validator against candidate file, returning exit code and combined output of the command
+func runValidator(options *consul.Validator, candidatePath string) (int, string, error) {
+	if options.Command == "" {
+		return -1, "", fmt.Errorf("validator must have `command`")
+	}
+	timeout := utils.DurationOrDefault(options.Timeout, utils.DefaultCommandTimeout)
+
+	arguments := make([]string, 0, len(options.Arguments))
+	for _, argument := range options.Arguments {
+		arguments = append(arguments, strings.ReplaceAll(argument, filePlaceholder, candidatePath))
+	}
+	process := exec.Command(strings.ReplaceAll(options.Command, filePlaceholder, candidatePath), arguments...)
+	var content bytes.Buffer
+	process.Stdout = &content
+	process.Stderr = &content
+	commandTimer, err := utils.StartWithTimeout(process, timeout)
+	if err != nil {
+		return -1, "", err
+	}
+	err = process.Wait()
+	timedOut := commandTimer.Stop()
+
+	output := strings.TrimSpace(content.String())
+	if len(output) > maxOutputLength {
+		output = output[:maxOutputLength] + "..."
+	}
+	exitCode := -1
+	if process.ProcessState != nil {
+		exitCode = process.ProcessState.ExitCode()
+	}
+	if timedOut {
+		return exitCode, output, fmt.Errorf("timed out after %s", timeout)
+	}
+	return exitCode, output, err
+}
+
+// describeValidator returns short description of the validator used in logs and notifications
+func describeValidator(options *consul.Validator) string {
+	return strings.TrimSpace(options.Command + " " + strings.Join(options.Arguments, " "))
+}
@@ -0,0 +1,97 @@
+//go:build !windows
+// +build !windows
+
+package validator
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leads-su/broker"
+	cfg "github.com/leads-su/consul-config-manager/pkg/config"
+	"github.com/leads-su/consul-config-manager/pkg/config/consul"
+)
+
+// newTestValidator creates validator with given configuration and candidate file with given content
+func newTestValidator(t *testing.T, content string, validators ...*consul.Validator) (*Validator, string) {
+	t.Helper()
+	config, err := cfg.Initialize(broker.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to initialize configuration - %s", err.Error())
+	}
+	config.Consul.Validators = validators
+	candidatePath := filepath.Join(t.TempDir(), "candidate")
+	if err = ioutil.WriteFile(candidatePath, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write candidate file - %s", err.Error())
+	}
+	return NewValidator(config), candidatePath
+}
+
+func TestValidCandidateIsAccepted(t *testing.T) {
+	validator, candidatePath := newTestValidator(t, "valid", &consul.Validator{
+		File:      "app/*",
+		Command:   "grep",
+		Arguments: []string{"-q", "valid", "{{file}}"},
+	})
+	if err := validator.Validate("app/main.env", candidatePath); err != nil {
+		t.Fatalf("expected candidate to be accepted, got %s", err.Error())
+	}
+	if heldBack := validator.HeldBackFiles(); len(heldBack) != 0 {
+		t.Fatalf("expected no held back files, got %#v", heldBack)
+	}
+}
+
+func TestInvalidCandidateIsHeldBackUntilFixed(t *testing.T) {
+	options := &consul.Validator{
+		File:      "app/*",
+		Command:   "sh",
+		Arguments: []string{"-c", "grep -qx valid {{file}} || { echo broken config; exit 2; }"},
+	}
+	validator, candidatePath := newTestValidator(t, "invalid", options)
+
+	if err := validator.Validate("app/main.env", candidatePath); err == nil {
+		t.Fatalf("expected candidate to be rejected")
+	}
+	heldBack := validator.HeldBackFiles()
+	if len(heldBack) != 1 || heldBack[0].ExitCode != 2 || heldBack[0].Output != "broken config" {
+		t.Fatalf("expected file to be held back with validator output, got %#v", heldBack)
+	}
+
+	if err := ioutil.WriteFile(candidatePath, []byte("valid"), 0600); err != nil {
+		t.Fatalf("failed to write candidate file - %s", err.Error())
+	}
+	if err := validator.Validate("app/main.env", candidatePath); err != nil {
+		t.Fatalf("expected fixed candidate to be accepted, got %s", err.Error())
+	}
+	if heldBack = validator.HeldBackFiles(); len(heldBack) != 0 {
+		t.Fatalf("expected file to be released, got %#v", heldBack)
+	}
+}
+
+func TestValidatorOfOtherFilesIsNotRun(t *testing.T) {
+	validator, candidatePath := newTestValidator(t, "invalid", &consul.Validator{File: "other/*", Command: "false"})
+	if err := validator.Validate("app/main.env", candidatePath); err != nil {
+		t.Fatalf("expected validator of other files not to run, got %s", err.Error())
+	}
+}
+
+func TestValidatorTimeoutKillsBackgroundProcesses(t *testing.T) {
+	validator, candidatePath := newTestValidator(t, "valid", &consul.Validator{
+		File:      "app/*",
+		Command:   "sh",
+		Arguments: []string{"-c", "sleep 30 & sleep 30"},
+		Timeout:   200 * time.Millisecond,
+	})
+
+	started := time.Now()
+	err := validator.Validate("app/main.env", candidatePath)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("validator was not stopped in time, took %s", elapsed)
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// DefaultCommandTimeout is a time after which external commands (hooks and validators) without `timeout` are killed
+const DefaultCommandTimeout = 30 * time.Second
+
+// MatchesFilePattern reports whether file path matches the pattern, empty pattern does not match anything
+func MatchesFilePattern(pattern, path string) bool {
+	if pattern == "" {
+		return false
+	}
+	matched, err := filepath.Match(pattern, path)
+	return err == nil && matched
+}
+
+// DurationOrDefault returns duration, or fallback if duration is not set
+func DurationOrDefault(duration, fallback time.Duration) time.Duration {
+	if duration <= 0 {
+		return fallback
+	}
+	return duration
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// CommandTimer kills command started with `StartWithTimeout` once its timeout passes
+type CommandTimer struct {
+	timer    *time.Timer
+	timedOut int32
+}
+
+// StartWithTimeout starts command in its own process group, the whole group is killed once timeout passes,
+// so processes the command started in background cannot keep its output open after it is killed
+func StartWithTimeout(process *exec.Cmd, timeout time.Duration) (*CommandTimer, error) {
+	setProcessGroup(process)
+	if err := process.Start(); err != nil {
+		return nil, err
+	}
+	commandTimer := &CommandTimer{}
+	commandTimer.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&commandTimer.timedOut, 1)
+		killProcessGroup(process)
+	})
+	return commandTimer, nil
+}
+
+// Stop stops the timer once command finished, reporting whether command was killed because of the timeout
+func (commandTimer *CommandTimer) Stop() bool {
+	commandTimer.timer.Stop()
+	return atomic.LoadInt32(&commandTimer.timedOut) == 1
+}
@@ -0,0 +1,47 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestStartWithTimeoutKillsBackgroundProcesses(t *testing.T) {
+	var output bytes.Buffer
+	process := exec.Command("sh", "-c", "sleep 30 & echo started; sleep 30")
+	process.Stdout = &output
+	started := time.Now()
+	commandTimer, err := StartWithTimeout(process, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to start command - %s", err.Error())
+	}
+	_ = process.Wait()
+
+	if !commandTimer.Stop() {
+		t.Fatalf("expected command to time out")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("background process kept output open for %s after timeout", elapsed)
+	}
+	if output.String() != "started\n" {
+		t.Fatalf("unexpected output %q", output.String())
+	}
+}
+
+func TestStartWithTimeoutDoesNotKillFinishedCommand(t *testing.T) {
+	process := exec.Command("true")
+	commandTimer, err := StartWithTimeout(process, time.Second)
+	if err != nil {
+		t.Fatalf("failed to start command - %s", err.Error())
+	}
+	if err = process.Wait(); err != nil {
+		t.Fatalf("command failed - %s", err.Error())
+	}
+	if commandTimer.Stop() {
+		t.Fatalf("expected command not to time out")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes command the leader of a new process group
+func setProcessGroup(process *exec.Cmd) {
+	if process.SysProcAttr == nil {
+		process.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	process.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup kills command along with every process in its group
+func killProcessGroup(process *exec.Cmd) {
+	if err := syscall.Kill(-process.Process.Pid, syscall.SIGKILL); err != nil {
+		_ = process.Process.Kill()
+	}
+}
@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package utils
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup makes command the root of a new process group
+func setProcessGroup(process *exec.Cmd) {
+	if process.SysProcAttr == nil {
+		process.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	process.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// killProcessGroup kills command along with every process it started
+func killProcessGroup(process *exec.Cmd) {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Process.Pid)).Run(); err != nil {
+		_ = process.Process.Kill()
+	}
+}